@@ -4,6 +4,7 @@ import (
 	"context"
 	"strings"
 
+	"github.com/go-cinch/common/log"
 	"github.com/go-kratos/kratos/v2/metadata"
 	"github.com/go-kratos/kratos/v2/transport"
 	jwtV4 "github.com/golang-jwt/jwt/v4"
@@ -17,6 +18,11 @@ const (
 	ClaimExpires  = "exp"
 )
 
+const (
+	LogKeyCode     = "user_code"
+	LogKeyPlatform = "user_platform"
+)
+
 type User struct {
 	Token    string `json:"token"`
 	Code     string `json:"code"`
@@ -74,6 +80,24 @@ func FromServerContext(ctx context.Context) (u *User) {
 	return
 }
 
+// LogExtractor print user code and platform of each line, use it by log.WithExtractor, empty values are omitted
+func LogExtractor() log.Extractor {
+	return func(ctx context.Context) (rp log.Fields) {
+		u := FromServerContext(ctx)
+		if u.Code == "" && u.Platform == "" {
+			return
+		}
+		rp = make(log.Fields, 2)
+		if u.Code != "" {
+			rp[LogKeyCode] = u.Code
+		}
+		if u.Platform != "" {
+			rp[LogKeyPlatform] = u.Platform
+		}
+		return
+	}
+}
+
 func TokenFromServerContext(ctx context.Context) (token string) {
 	if tr, ok := transport.FromServerContext(ctx); ok {
 		auths := strings.SplitN(tr.RequestHeader().Get("Authorization"), " ", 2)
@@ -0,0 +1,183 @@
+package log
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+)
+
+// OverflowPolicy what AsyncWriter does when buffer is full
+type OverflowPolicy int
+
+const (
+	// OverflowBlock wait until buffer has space
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDropNewest drop the line being written
+	OverflowDropNewest
+	// OverflowDropOldest drop the oldest line in buffer
+	OverflowDropOldest
+)
+
+type flusher interface {
+	Flush() error
+}
+
+var (
+	_ io.WriteCloser = (*AsyncWriter)(nil)
+	_ flusher        = (*AsyncWriter)(nil)
+)
+
+// AsyncWriter write lines to w in background by a ring buffer,
+// call Flush/Close before exit otherwise buffered lines are lost
+type AsyncWriter struct {
+	ops     AsyncOptions
+	w       io.Writer
+	lock    sync.Mutex
+	cond    *sync.Cond
+	buf     [][]byte
+	head    int
+	size    int
+	writing bool
+	closed  bool
+	dropped atomic.Uint64
+	done    chan struct{}
+}
+
+// NewAsyncWriter start a goroutine writes buffered lines to w
+func NewAsyncWriter(w io.Writer, options ...func(*AsyncOptions)) *AsyncWriter {
+	ops := getAsyncOptionsOrSetDefault(nil)
+	for _, f := range options {
+		f(ops)
+	}
+	aw := &AsyncWriter{
+		ops:  *ops,
+		w:    w,
+		buf:  make([][]byte, ops.size),
+		done: make(chan struct{}),
+	}
+	aw.cond = sync.NewCond(&aw.lock)
+	go aw.loop()
+	return aw
+}
+
+// Write copy p into buffer, it never returns error of w
+func (aw *AsyncWriter) Write(p []byte) (n int, err error) {
+	line := make([]byte, len(p))
+	copy(line, p)
+	n = len(p)
+	aw.lock.Lock()
+	defer aw.lock.Unlock()
+	if aw.closed {
+		err = os.ErrClosed
+		return
+	}
+	for aw.size == len(aw.buf) {
+		switch aw.ops.policy {
+		case OverflowDropNewest:
+			aw.dropped.Add(1)
+			return
+		case OverflowDropOldest:
+			aw.buf[aw.head] = nil
+			aw.head = (aw.head + 1) % len(aw.buf)
+			aw.size--
+			aw.dropped.Add(1)
+		default:
+			aw.cond.Wait()
+			if aw.closed {
+				err = os.ErrClosed
+				return
+			}
+		}
+	}
+	aw.buf[(aw.head+aw.size)%len(aw.buf)] = line
+	aw.size++
+	aw.cond.Broadcast()
+	return
+}
+
+func (aw *AsyncWriter) loop() {
+	defer close(aw.done)
+	lines := make([][]byte, 0, len(aw.buf))
+	for {
+		aw.lock.Lock()
+		for aw.size == 0 && !aw.closed {
+			aw.cond.Wait()
+		}
+		if aw.size == 0 && aw.closed {
+			aw.lock.Unlock()
+			return
+		}
+		lines = lines[:0]
+		for ; aw.size > 0; aw.size-- {
+			lines = append(lines, aw.buf[aw.head])
+			aw.buf[aw.head] = nil
+			aw.head = (aw.head + 1) % len(aw.buf)
+		}
+		aw.writing = true
+		aw.cond.Broadcast()
+		aw.lock.Unlock()
+
+		for _, line := range lines {
+			if _, err := aw.w.Write(line); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to write to log, %v\n", err)
+			}
+		}
+
+		aw.lock.Lock()
+		aw.writing = false
+		aw.cond.Broadcast()
+		aw.lock.Unlock()
+	}
+}
+
+// Flush wait until buffered lines are written to w, w is also flushed if it has Flush() error
+func (aw *AsyncWriter) Flush() (err error) {
+	aw.lock.Lock()
+	for aw.size > 0 || aw.writing {
+		aw.cond.Wait()
+	}
+	aw.lock.Unlock()
+	if f, ok := aw.w.(flusher); ok {
+		err = f.Flush()
+	}
+	return
+}
+
+// Close flush buffered lines and stop background goroutine, w is not closed
+func (aw *AsyncWriter) Close() (err error) {
+	aw.lock.Lock()
+	if aw.closed {
+		aw.lock.Unlock()
+		return
+	}
+	aw.closed = true
+	aw.cond.Broadcast()
+	aw.lock.Unlock()
+	<-aw.done
+	if f, ok := aw.w.(flusher); ok {
+		err = f.Flush()
+	}
+	return
+}
+
+// Dropped count of lines dropped by overflow policy
+func (aw *AsyncWriter) Dropped() uint64 {
+	return aw.dropped.Load()
+}
+
+// flushWriter flush w and writers of MultiWriter
+func flushWriter(w io.Writer) (err error) {
+	switch v := w.(type) {
+	case *multiWriter:
+		for _, item := range v.ws {
+			if e := flushWriter(item); e != nil && err == nil {
+				err = e
+			}
+		}
+	case flusher:
+		err = v.Flush()
+	}
+	return
+}
@@ -0,0 +1,101 @@
+package log
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// blockWriter block writes until release is closed
+type blockWriter struct {
+	lock    sync.Mutex
+	buf     bytes.Buffer
+	release chan struct{}
+}
+
+func (w *blockWriter) Write(p []byte) (int, error) {
+	<-w.release
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return w.buf.Write(p)
+}
+
+func (w *blockWriter) String() string {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return w.buf.String()
+}
+
+func TestAsyncWriter(t *testing.T) {
+	for policy, expect := range map[OverflowPolicy]string{
+		OverflowDropNewest: "0,1,2,",
+		OverflowDropOldest: "0,4,5,",
+		OverflowBlock:      "0,1,2,3,4,5,",
+	} {
+		bw := &blockWriter{release: make(chan struct{})}
+		aw := NewAsyncWriter(bw, WithAsyncSize(2), WithAsyncOverflow(policy))
+		_, _ = aw.Write([]byte("0,"))
+		// wait until 0 is taken by background goroutine
+		aw.lock.Lock()
+		for aw.size > 0 {
+			aw.cond.Wait()
+		}
+		aw.lock.Unlock()
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, item := range []string{"1,", "2,", "3,", "4,", "5,"} {
+				_, _ = aw.Write([]byte(item))
+			}
+		}()
+		if policy == OverflowBlock {
+			// writer of 3 is blocked until 0 is written
+			time.Sleep(50 * time.Millisecond)
+			aw.lock.Lock()
+			size := aw.size
+			aw.lock.Unlock()
+			if aw.Dropped() != 0 || size != 2 {
+				t.Errorf("expect writer blocked")
+			}
+			close(bw.release)
+			wg.Wait()
+		} else {
+			wg.Wait()
+			if aw.Dropped() != 3 {
+				t.Errorf("policy %d expect 3 dropped, got %d", policy, aw.Dropped())
+			}
+			close(bw.release)
+		}
+		if err := aw.Flush(); err != nil {
+			t.Fatal(err)
+		}
+		if got := bw.String(); got != expect {
+			t.Errorf("policy %d expect %s, got %s", policy, expect, got)
+		}
+		if err := aw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := aw.Write([]byte("6,")); err == nil {
+			t.Errorf("expect write after close failed")
+		}
+	}
+}
+
+func TestWrapper_Flush(t *testing.T) {
+	var buf syncBuffer
+	aw := NewAsyncWriter(&buf)
+	defer aw.Close()
+	w := NewWrapper(WithOutput(aw), WithCaller(false))
+	for i := 0; i < 100; i++ {
+		w.Info("test info %d", i)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(buf.String(), "test info"); n != 100 {
+		t.Errorf("expect 100 lines after flush, got %d", n)
+	}
+}
@@ -0,0 +1,66 @@
+package log
+
+import (
+	"context"
+	"io"
+	"testing"
+)
+
+func benchmarkWrapper(b *testing.B, w *Wrapper) {
+	ctx := context.Background()
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			w.WithContext(ctx).WithFields(Fields{"user": "bob", "count": 1}).Info("test info %d", 1)
+		}
+	})
+}
+
+func BenchmarkLogrus(b *testing.B) {
+	benchmarkWrapper(b, NewWrapper(WithOutput(io.Discard), WithCaller(false)))
+}
+
+func BenchmarkLogrusCaller(b *testing.B) {
+	benchmarkWrapper(b, NewWrapper(WithOutput(io.Discard)))
+}
+
+func BenchmarkLogrusJSON(b *testing.B) {
+	benchmarkWrapper(b, NewWrapper(WithOutput(io.Discard), WithCaller(false), WithJSON(true)))
+}
+
+func BenchmarkSlog(b *testing.B) {
+	benchmarkWrapper(b, NewWrapper(WithOutput(io.Discard), WithCaller(false), WithSlog(true)))
+}
+
+func BenchmarkSlogCaller(b *testing.B) {
+	benchmarkWrapper(b, NewWrapper(WithOutput(io.Discard), WithSlog(true)))
+}
+
+func BenchmarkDisabled(b *testing.B) {
+	benchmarkWrapper(b, NewWrapper(WithOutput(io.Discard), WithLevel(WarnLevel)))
+}
+
+func BenchmarkWrapper_disabled(b *testing.B) {
+	w := NewWrapper(WithOutput(io.Discard), WithLevel(WarnLevel))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		w.Info("test info")
+	}
+}
+
+func BenchmarkLogger_disabled(b *testing.B) {
+	l := New(WithOutput(io.Discard), WithLevel(WarnLevel))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		l.Logf(InfoLevel, "test info %d", i)
+	}
+}
+
+func BenchmarkAsync(b *testing.B) {
+	aw := NewAsyncWriter(io.Discard, WithAsyncSize(4096))
+	defer aw.Close()
+	benchmarkWrapper(b, NewWrapper(WithOutput(aw), WithCaller(false)))
+}
@@ -4,22 +4,21 @@ import (
 	"context"
 	"os"
 	"path"
+	"reflect"
 	"regexp"
 	"runtime"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/go-kratos/kratos/v2/log"
 )
 
 var (
-	logDir      = ""
-	commonDir   = ""
-	notSkipDirs = []string{
-		"transport/grpc/server",
-		"transport/http/server",
-		"middleware/logging/logging",
-	}
+	logDir    = ""
+	commonDir = ""
+	// commonPkg package path of this library, github.com/go-cinch/common
+	commonPkg = ""
 )
 
 func init() {
@@ -28,49 +27,132 @@ func init() {
 	file = regexp.MustCompile(`@v\d+(\.\d+)+(-\d+\.\d+-[a-f0-9]+)?|@`).ReplaceAllString(file, "")
 	logDir = regexp.MustCompile(`caller/caller\.go`).ReplaceAllString(file, "")
 	commonDir = regexp.MustCompile(`log/caller/caller\.go`).ReplaceAllString(file, "")
+	// function name is the same under vendor and -trimpath
+	fn := runtime.FuncForPC(reflect.ValueOf(Caller).Pointer()).Name()
+	commonPkg = strings.TrimSuffix(funcPackage(fn), "/log/caller")
 }
 
+// Caller valuer of the first frame not skipped, frames are resolved once and cached by pc
 func Caller(options ...func(*Options)) log.Valuer {
 	ops := getOptionsOrSetDefault(nil)
 	for _, f := range options {
 		f(ops)
 	}
+	c := &resolver{
+		ops: *ops,
+		pcs: sync.Pool{
+			New: func() interface{} {
+				pcs := make([]uintptr, ops.depth)
+				return &pcs
+			},
+		},
+	}
 	return func(context.Context) interface{} {
-		return caller(*ops)
+		return c.caller()
 	}
 }
 
-func caller(ops Options) string {
-	// the second caller usually from gorm internal, so set i start from 2
-	for i := 0; i < 15; i++ {
-		_, file, line, ok := runtime.Caller(i)
-		var skip bool
-		if strings.Contains(file, commonDir) {
-			skip = true
-		}
-		for _, item := range ops.skips {
-			if strings.Contains(file, item) {
-				skip = true
-				break
-			}
-		}
-		for _, item := range ops.keeps {
-			if strings.Contains(file, item) {
-				skip = false
-			}
+type resolver struct {
+	ops Options
+	pcs sync.Pool
+	// cache pc => caller, empty string means skipped
+	cache sync.Map
+}
+
+func (r *resolver) caller() (rs string) {
+	pcs := r.pcs.Get().(*[]uintptr)
+	defer r.pcs.Put(pcs)
+	// skip runtime.Callers, caller and valuer
+	n := runtime.Callers(3, *pcs)
+	for _, pc := range (*pcs)[:n] {
+		if v, ok := r.cache.Load(pc); ok {
+			rs = v.(string)
+		} else {
+			rs = r.resolve(pc)
+			r.cache.Store(pc, rs)
 		}
-		if strings.Contains(file, "go-kratos") && containsString(notSkipDirs, file) {
-			skip = false
+		if rs != "" {
+			return
 		}
-		if skip {
-			continue
+	}
+	return
+}
+
+// resolve pc may contain inlined frames, the innermost one not skipped is used
+func (r *resolver) resolve(pc uintptr) string {
+	frames := runtime.CallersFrames([]uintptr{pc})
+	for {
+		frame, more := frames.Next()
+		if frame.File != "" && !r.skip(frame) {
+			return format(frame, r.ops)
 		}
-		if ok && (!strings.HasPrefix(file, logDir) || strings.HasSuffix(file, "_test.go")) && !strings.Contains(file, "src/runtime") {
-			return removeBaseDir(strings.Join([]string{file, strconv.Itoa(line)}, ":"), ops)
+		if !more {
+			return ""
 		}
 	}
+}
+
+// skip priority: keep > skip by file > test file > skip by package
+func (r *resolver) skip(frame runtime.Frame) bool {
+	pkg := funcPackage(frame.Function)
+	if hasPackage(r.ops.keepPackages, pkg) || containsString(r.ops.keeps, frame.File) {
+		return false
+	}
+	if containsString(r.ops.skips, frame.File) {
+		return true
+	}
+	// caller of tests is kept, even if it's in skipped package
+	if strings.HasSuffix(frame.File, "_test.go") {
+		return false
+	}
+	return hasPackage(r.ops.skipPackages, pkg)
+}
 
-	return ""
+func format(frame runtime.Frame, ops Options) string {
+	var s string
+	if ops.pkg {
+		s = strings.Join([]string{funcPackage(frame.Function), path.Base(frame.File)}, "/")
+	} else {
+		s = removeBaseDir(frame.File, ops)
+	}
+	s = strings.Join([]string{s, strconv.Itoa(frame.Line)}, ":")
+	if ops.function && frame.Function != "" {
+		s = strings.Join([]string{s, shortFunction(frame.Function)}, " ")
+	}
+	return s
+}
+
+// funcPackage package path of function name, such as
+// github.com/go-cinch/common/log.(*logrusLog).Log => github.com/go-cinch/common/log
+func funcPackage(fn string) string {
+	i := strings.LastIndex(fn, "/")
+	if j := strings.Index(fn[i+1:], "."); j >= 0 {
+		fn = fn[:i+1+j]
+	}
+	// dot in the last element is escaped, gopkg.in/yaml%2ev3
+	fn = strings.ReplaceAll(fn, "%2e", ".")
+	// GOPATH vendor
+	if i = strings.LastIndex(fn, "/vendor/"); i >= 0 {
+		fn = fn[i+len("/vendor/"):]
+	}
+	return strings.TrimPrefix(fn, "vendor/")
+}
+
+// shortFunction function name with the last element of package path, such as biz.(*UserUseCase).Create
+func shortFunction(fn string) string {
+	if i := strings.LastIndex(fn, "/"); i >= 0 {
+		fn = fn[i+1:]
+	}
+	return fn
+}
+
+func hasPackage(pkgs []string, pkg string) bool {
+	for _, item := range pkgs {
+		if pkg == item || strings.HasPrefix(pkg, item+"/") {
+			return true
+		}
+	}
+	return false
 }
 
 func removeBaseDir(s string, ops Options) string {
@@ -1,7 +1,10 @@
 package caller
 
 import (
+	"context"
+	"path"
 	"regexp"
+	"strings"
 	"testing"
 )
 
@@ -28,3 +31,56 @@ func TestCaller(t *testing.T) {
 		}
 	}
 }
+
+func TestFuncPackage(t *testing.T) {
+	cases := map[string]string{
+		"github.com/go-cinch/common/log.(*logrusLog).Log":               "github.com/go-cinch/common/log",
+		"github.com/go-cinch/common/log/caller.Caller.func1":            "github.com/go-cinch/common/log/caller",
+		"github.com/go-kratos/kratos/v2/transport/http.(*Server).Serve": "github.com/go-kratos/kratos/v2/transport/http",
+		"gopkg.in/yaml%2ev3.Unmarshal":                                  "gopkg.in/yaml.v3",
+		"app/vendor/github.com/pkg/errors.New":                          "github.com/pkg/errors",
+		"main.main":                                                     "main",
+		"runtime.goexit":                                                "runtime",
+	}
+	for fn, pkg := range cases {
+		if rs := funcPackage(fn); rs != pkg {
+			t.Errorf("expect %s, got %s", pkg, rs)
+		}
+	}
+	if commonPkg != "github.com/go-cinch/common" {
+		t.Errorf("expect common package, got %s", commonPkg)
+	}
+}
+
+func TestCaller_test(t *testing.T) {
+	rs := Caller()(context.Background()).(string)
+	if !strings.HasSuffix(path.Dir(rs), "log/caller") || !strings.Contains(rs, "caller_test.go:") {
+		t.Errorf("expect caller of test, got %s", rs)
+	}
+
+	rs = Caller(WithPackage(true), WithFunction(true))(context.Background()).(string)
+	if !strings.HasPrefix(rs, "github.com/go-cinch/common/log/caller/caller_test.go:") || !strings.HasSuffix(rs, " caller.TestCaller_test") {
+		t.Errorf("expect package and function, got %s", rs)
+	}
+}
+
+func helper(valuer func(context.Context) interface{}) string {
+	return valuer(context.Background()).(string)
+}
+
+func TestCaller_depth(t *testing.T) {
+	valuer := Caller(WithFunction(true))
+	if rs := helper(valuer); !strings.HasSuffix(rs, " caller.helper") {
+		t.Errorf("expect helper, got %s", rs)
+	}
+	// cached result is the same
+	if rs := helper(valuer); !strings.HasSuffix(rs, " caller.helper") {
+		t.Errorf("expect cached helper, got %s", rs)
+	}
+
+	// frames of skipped package are not found in depth
+	valuer = Caller(WithMaxDepth(1), WithSkip("caller_test.go"))
+	if rs := helper(valuer); rs != "" {
+		t.Errorf("expect empty, got %s", rs)
+	}
+}
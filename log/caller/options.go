@@ -1,28 +1,70 @@
 package caller
 
 type Options struct {
-	skips   []string
-	keeps   []string
-	source  bool
-	prefix  string
-	level   int
-	version bool
+	skips        []string
+	keeps        []string
+	skipPackages []string
+	keepPackages []string
+	depth        int
+	source       bool
+	prefix       string
+	level        int
+	version      bool
+	function     bool
+	pkg          bool
 }
 
-// WithSkip if line contains s, skip it
+// WithSkip if file contains s, skip it, prefer WithSkipPackage which works under vendor and -trimpath
 func WithSkip(s string) func(*Options) {
 	return func(options *Options) {
 		getOptionsOrSetDefault(options).skips = append(getOptionsOrSetDefault(options).skips, s)
 	}
 }
 
-// WithKeep if line contains s, keep it
+// WithKeep if file contains s, keep it
 func WithKeep(s string) func(*Options) {
 	return func(options *Options) {
 		getOptionsOrSetDefault(options).keeps = append(getOptionsOrSetDefault(options).keeps, s)
 	}
 }
 
+// WithSkipPackage skip frames of packages and their sub packages
+func WithSkipPackage(pkg ...string) func(*Options) {
+	return func(options *Options) {
+		getOptionsOrSetDefault(options).skipPackages = append(getOptionsOrSetDefault(options).skipPackages, pkg...)
+	}
+}
+
+// WithKeepPackage keep frames of packages and their sub packages, it has higher priority than skip
+func WithKeepPackage(pkg ...string) func(*Options) {
+	return func(options *Options) {
+		getOptionsOrSetDefault(options).keepPackages = append(getOptionsOrSetDefault(options).keepPackages, pkg...)
+	}
+}
+
+// WithMaxDepth max frames to find caller, default 15
+func WithMaxDepth(depth int) func(*Options) {
+	return func(options *Options) {
+		if depth > 0 {
+			getOptionsOrSetDefault(options).depth = depth
+		}
+	}
+}
+
+// WithFunction append function name, such as biz.(*UserUseCase).Create
+func WithFunction(flag bool) func(*Options) {
+	return func(options *Options) {
+		getOptionsOrSetDefault(options).function = flag
+	}
+}
+
+// WithPackage show package path and file name instead of file path, such as github.com/go-cinch/layout/internal/biz/user.go:10
+func WithPackage(flag bool) func(*Options) {
+	return func(options *Options) {
+		getOptionsOrSetDefault(options).pkg = flag
+	}
+}
+
 // WithSource show common library source code or not
 func WithSource(flag bool) func(*Options) {
 	return func(options *Options) {
@@ -48,15 +90,27 @@ func getOptionsOrSetDefault(options *Options) *Options {
 	if options == nil {
 		return &Options{
 			skips: []string{
-				"gorm.io",
-				"go-kratos",
-				"golang.org/x/sync",
 				"addx-web3-go-common.git",
 				".gen.go",
 			},
 			keeps: []string{
 				"addx-web3-go-common.git/middleware/logging",
 			},
+			skipPackages: []string{
+				commonPkg,
+				"runtime",
+				"log/slog",
+				"gorm.io",
+				"github.com/go-kratos/kratos",
+				"github.com/sirupsen/logrus",
+				"golang.org/x/sync",
+			},
+			keepPackages: []string{
+				"github.com/go-kratos/kratos/v2/transport/grpc",
+				"github.com/go-kratos/kratos/v2/transport/http",
+				"github.com/go-kratos/kratos/v2/middleware/logging",
+			},
+			depth:   15,
 			source:  false,
 			level:   2,
 			version: true,
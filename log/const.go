@@ -3,4 +3,5 @@ package log
 const (
 	ErrKey    = "err"
 	CallerKey = "caller"
+	LoggerKey = "logger"
 )
@@ -1,6 +1,9 @@
 package log
 
-import "context"
+import (
+	"context"
+	"net/http"
+)
 
 var DefaultWrapper *Wrapper
 
@@ -47,3 +50,22 @@ func WithFields(fields Fields) *Wrapper {
 func WithContext(ctx context.Context) *Wrapper {
 	return DefaultWrapper.WithContext(ctx)
 }
+
+func Flush() error {
+	return DefaultWrapper.Flush()
+}
+
+func Named(name string) *Wrapper {
+	return DefaultWrapper.Named(name)
+}
+
+func SetLevel(level Level) {
+	DefaultWrapper.SetLevel(level)
+}
+
+// LevelHandler level handler of DefaultWrapper, DefaultWrapper is got in each request
+func LevelHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		DefaultWrapper.LevelHandler().ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,128 @@
+package log
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	pkgErrors "github.com/pkg/errors"
+)
+
+// StackSuffix stack of error field k is printed as k.stack in text format
+const StackSuffix = "stack"
+
+const errorMaxDepth = 16
+
+type stackTracer interface {
+	StackTrace() pkgErrors.StackTrace
+}
+
+// statusError kratos errors.Error, matched by methods so that log doesn't depend on grpc
+type statusError interface {
+	error
+	GetCode() int32
+	GetReason() string
+	GetMessage() string
+	GetMetadata() map[string]string
+}
+
+// ErrorView JSON format of error, it replaces error values of fields passed to Hook
+type ErrorView struct {
+	Message  string            `json:"message"`
+	Type     string            `json:"type"`
+	Reason   string            `json:"reason,omitempty"`
+	Code     int32             `json:"code,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+	Causes   []string          `json:"causes,omitempty"`
+	Stack    string            `json:"stack,omitempty"`
+}
+
+// renderErrors replace error values of fields:
+// JSON format is an object with message, type, kratos reason/code/metadata, cause chain and stack,
+// text format is a one-line cause chain, stack is another field in one line.
+// stack is only rendered at error level and above
+func (o Options) renderErrors(level Level, fields Fields) {
+	for k, v := range fields {
+		err, ok := v.(error)
+		if !ok || err == nil {
+			continue
+		}
+		layers, root, stack := unwrapError(err)
+		if !o.stack() || !ErrorLevel.Enabled(level) {
+			stack = ""
+		}
+		msg := strings.ReplaceAll(strings.Join(layers, ": "), "\n", `\n`)
+		if !o.json {
+			fields[k] = msg
+			if stack != "" {
+				fields[strings.Join([]string{k, StackSuffix}, ".")] = compactStack(stack)
+			}
+			continue
+		}
+		view := ErrorView{
+			Message: msg,
+			Type:    fmt.Sprintf("%T", root),
+			Stack:   stack,
+		}
+		if len(layers) > 1 {
+			view.Causes = layers[1:]
+		}
+		var se statusError
+		if errors.As(err, &se) {
+			view.Reason = se.GetReason()
+			view.Code = se.GetCode()
+			view.Metadata = se.GetMetadata()
+		}
+		fields[k] = view
+	}
+}
+
+// stack print stack or not, default only JSON format since text stack is long
+func (o Options) stack() bool {
+	if o.errorStack != nil {
+		return *o.errorStack
+	}
+	return o.json
+}
+
+// compactStack keep text line single, each frame is "func file:line" and frames are joined by ';'
+func compactStack(stack string) string {
+	lines := strings.Split(stack, "\n")
+	frames := make([]string, 0, len(lines)/2+1)
+	for i := 0; i < len(lines); i++ {
+		frame := lines[i]
+		if i+1 < len(lines) && strings.HasPrefix(lines[i+1], "\t") {
+			frame = strings.Join([]string{frame, strings.TrimPrefix(lines[i+1], "\t")}, " ")
+			i++
+		}
+		frames = append(frames, frame)
+	}
+	return strings.Join(frames, ";")
+}
+
+// unwrapError own message of each error in chain, the root cause and the deepest stack
+func unwrapError(err error) (layers []string, root error, stack string) {
+	for e, i := err, 0; e != nil && i < errorMaxDepth; e, i = errors.Unwrap(e), i+1 {
+		root = e
+		if st, ok := e.(stackTracer); ok {
+			stack = strings.TrimPrefix(fmt.Sprintf("%+v", st.StackTrace()), "\n")
+		}
+		var msg string
+		if se, ok := e.(statusError); ok {
+			msg = fmt.Sprintf("%s(reason=%s code=%d)", se.GetMessage(), se.GetReason(), se.GetCode())
+		} else {
+			msg = e.Error()
+			// remove message of cause, fmt.Errorf/pkg/errors join them by ': '
+			if next := errors.Unwrap(e); next != nil {
+				msg = strings.TrimSuffix(strings.TrimSuffix(msg, next.Error()), ": ")
+			}
+		}
+		if msg != "" {
+			layers = append(layers, msg)
+		}
+	}
+	if len(layers) == 0 {
+		layers = append(layers, err.Error())
+	}
+	return
+}
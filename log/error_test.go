@@ -0,0 +1,78 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	kratosErrors "github.com/go-kratos/kratos/v2/errors"
+	pkgErrors "github.com/pkg/errors"
+)
+
+func newTestError() error {
+	e := kratosErrors.BadRequest("INVALID_ARGS", "invalid args").WithMetadata(map[string]string{"field": "name"})
+	return fmt.Errorf("create user: %w", pkgErrors.Wrap(e, "validate"))
+}
+
+func TestRenderErrors_text(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWrapper(WithOutput(&buf), WithCaller(false))
+	w.WithError(newTestError()).Error("test error")
+	expect := "err=create user: validate: invalid args(reason=INVALID_ARGS code=400)"
+	if !strings.Contains(buf.String(), expect) || strings.Contains(buf.String(), "err.stack") {
+		t.Errorf("expect compact chain without stack by default, got %s", buf.String())
+	}
+
+	buf.Reset()
+	w = NewWrapper(WithOutput(&buf), WithCaller(false), WithErrorStack(true))
+	err := newTestError()
+	w.WithError(err).Warn("test warn")
+	w.WithError(err).Error("test error")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expect one line each, got %q", buf.String())
+	}
+	if !strings.Contains(lines[0], expect) || strings.Contains(lines[0], "err.stack") {
+		t.Errorf("expect compact chain without stack, got %s", lines[0])
+	}
+	if !strings.Contains(lines[1], expect) || !strings.Contains(lines[1], "err.stack=") ||
+		!strings.Contains(lines[1], "newTestError") || !strings.Contains(lines[1], "error_test.go:") || !strings.Contains(lines[1], ";") {
+		t.Errorf("expect compact chain with one-line stack, got %s", lines[1])
+	}
+}
+
+func TestRenderErrors_json(t *testing.T) {
+	for _, flag := range []bool{false, true} {
+		var buf bytes.Buffer
+		w := NewWrapper(WithOutput(&buf), WithJSON(true), WithSlog(flag), WithCaller(false))
+		w.WithError(newTestError()).Error("test error")
+
+		var m struct {
+			Err ErrorView `json:"err"`
+		}
+		err := json.Unmarshal(buf.Bytes(), &m)
+		if err != nil {
+			t.Fatalf("expect json, got %s", buf.String())
+		}
+		v := m.Err
+		if v.Message != "create user: validate: invalid args(reason=INVALID_ARGS code=400)" || v.Type != "*errors.Error" ||
+			v.Reason != "INVALID_ARGS" || v.Code != 400 || v.Metadata["field"] != "name" {
+			t.Errorf("unexpected error view %+v", v)
+		}
+		if len(v.Causes) != 2 || v.Causes[0] != "validate" || !strings.Contains(v.Stack, "newTestError") {
+			t.Errorf("expect causes and stack, got %v %s", v.Causes, v.Stack)
+		}
+	}
+}
+
+func TestWithErrorStack(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWrapper(WithOutput(&buf), WithCaller(false), WithJSON(true), WithErrorStack(false))
+	w.WithError(pkgErrors.New("something error")).Error("test error")
+	if !strings.Contains(buf.String(), `"message":"something error"`) || strings.Contains(buf.String(), `"stack"`) {
+		t.Errorf("expect no stack, got %s", buf.String())
+	}
+}
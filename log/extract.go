@@ -0,0 +1,48 @@
+package log
+
+import (
+	"context"
+
+	"github.com/go-kratos/kratos/v2/metadata"
+)
+
+const (
+	RequestIDKey = "request_id"
+	// RequestIDMetadata request id propagated by kratos metadata middleware
+	RequestIDMetadata = "x-md-global-request-id"
+)
+
+// Extractor extract fields from context of WithContext, return nil if there is nothing to print
+type Extractor func(ctx context.Context) Fields
+
+// RequestIDExtractor request_id from RequestIDFromContext
+func RequestIDExtractor() Extractor {
+	return func(ctx context.Context) Fields {
+		id := RequestIDFromContext(ctx)
+		if id == "" {
+			return nil
+		}
+		return Fields{
+			RequestIDKey: id,
+		}
+	}
+}
+
+type requestIDCtx struct{}
+
+// NewRequestIDContext bind request id to ctx
+func NewRequestIDContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDCtx{}, id)
+}
+
+// RequestIDFromContext get request id from NewRequestIDContext or RequestIDMetadata
+func RequestIDFromContext(ctx context.Context) (id string) {
+	if v, ok := ctx.Value(requestIDCtx{}).(string); ok && v != "" {
+		id = v
+		return
+	}
+	if md, ok := metadata.FromServerContext(ctx); ok {
+		id = md.Get(RequestIDMetadata)
+	}
+	return
+}
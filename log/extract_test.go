@@ -0,0 +1,42 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestWithExtractor(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWrapper(
+		WithOutput(&buf),
+		WithCaller(false),
+		WithRequestID(true),
+		WithExtractor(func(ctx context.Context) Fields {
+			v, _ := ctx.Value("tenant").(string)
+			return Fields{"tenant_id": v}
+		}),
+	)
+	ctx := NewRequestIDContext(context.Background(), "req-1")
+	ctx = context.WithValue(ctx, "tenant", "t1")
+
+	w.WithContext(ctx).WithField("request_id", "override").Info("with context")
+	w.WithContext(context.Background()).Info("empty context")
+	w.Info("no context")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expect 3 lines, got %q", buf.String())
+	}
+	for _, item := range []string{"request_id=override", "tenant_id=t1"} {
+		if !strings.Contains(lines[0], item) {
+			t.Errorf("expect %s in %s", item, lines[0])
+		}
+	}
+	for _, line := range lines[1:] {
+		if strings.Contains(line, "request_id") || strings.Contains(line, "tenant_id") {
+			t.Errorf("expect no context fields, got %s", line)
+		}
+	}
+}
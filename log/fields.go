@@ -0,0 +1,118 @@
+package log
+
+import (
+	"context"
+	"sync"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+var fieldsPool = sync.Pool{
+	New: func() interface{} {
+		return make(Fields, 16)
+	},
+}
+
+func getFields() Fields {
+	return fieldsPool.Get().(Fields)
+}
+
+// putFields give fields back to pool, fields must not be used after it
+func putFields(fields Fields) {
+	// avoid keeping huge maps
+	if len(fields) > 64 {
+		return
+	}
+	clear(fields)
+	fieldsPool.Put(fields)
+}
+
+// fieldChain fields of each WithFields, they are merged only when a line is written.
+// fields of parent override the child's, same as before
+type fieldChain struct {
+	fields Fields
+	parent *fieldChain
+}
+
+func (c *fieldChain) with(fields Fields) *fieldChain {
+	if len(fields) == 0 {
+		return c
+	}
+	return &fieldChain{
+		fields: copyFields(fields),
+		parent: c,
+	}
+}
+
+// entry state shared by logrus and slog backend, it's immutable, With* return a new one
+type entry struct {
+	ops    *Options
+	ctx    context.Context
+	fields *fieldChain
+}
+
+func (e entry) withFields(fields Fields) entry {
+	e.fields = e.fields.with(fields)
+	return e
+}
+
+func (e entry) withContext(ctx context.Context) entry {
+	e.ctx = ctx
+	return e
+}
+
+func (e entry) named(name string) entry {
+	ops := namedOptions(*e.ops, name)
+	e.ops = &ops
+	return e
+}
+
+// enabled check level first, message template is only sampled when level is enabled
+func (e entry) enabled(level Level) bool {
+	return e.ops.Level().Enabled(level)
+}
+
+// values merge extracted fields, fields, valuers and caller into a pooled map,
+// then render errors and redact them. caller is the last since it's the most expensive one
+func (e entry) values(level Level) Fields {
+	ops := e.ops
+	ns := getFields()
+	if e.ctx != nil {
+		for _, f := range ops.extractors {
+			for k, v := range f(e.ctx) {
+				ns[k] = v
+			}
+		}
+	}
+	for c := e.fields; c != nil; c = c.parent {
+		for k, v := range c.fields {
+			ns[k] = v
+		}
+	}
+	if ops.name != "" {
+		ns[LoggerKey] = ops.name
+	}
+	for k, v := range ops.valuers {
+		if f, ok := v.(log.Valuer); ok {
+			v = f(e.ctx)
+		}
+		ns[k] = v
+	}
+	if ops.callerValuer != nil {
+		ns[CallerKey] = ops.callerValuer(e.ctx)
+	}
+	if ops.skipEmpty {
+		for k, v := range ns {
+			if str, ok := v.(string); ok && str == "" {
+				delete(ns, k)
+			}
+		}
+	}
+	ops.renderErrors(level, ns)
+	if ops.redactor != nil {
+		rs := ops.redactor.Fields(ns)
+		putFields(ns)
+		ns = rs
+	}
+	return ns
+}
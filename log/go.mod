@@ -4,6 +4,7 @@ go 1.23
 
 require (
 	github.com/go-kratos/kratos/v2 v2.8.3
+	github.com/pkg/errors v0.9.1
 	github.com/sirupsen/logrus v1.9.3
 )
 
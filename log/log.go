@@ -1,6 +1,11 @@
 package log
 
-import "context"
+import (
+	"context"
+	"strings"
+
+	"github.com/go-cinch/common/log/caller"
+)
 
 // Fields type, used to pass to `WithFields`.
 type Fields map[string]interface{}
@@ -14,6 +19,24 @@ type Logger interface {
 	Logf(level Level, format string, v ...interface{})
 }
 
+// NamedLogger optional interface of Logger, built-in loggers support named child logger with its own level
+type NamedLogger interface {
+	Named(name string) Logger
+}
+
+// Hook receive each line after it is written, such as log/otel emits it as OpenTelemetry log record,
+// fields are reused after Fire returns, copy them if they are kept.
+// hook which has Flush() error is flushed by Wrapper.Flush and before Wrapper.Fatal exits
+type Hook interface {
+	Fire(ctx context.Context, level Level, msg string, fields Fields)
+}
+
+func (o Options) fire(ctx context.Context, level Level, msg string, fields Fields) {
+	for _, h := range o.hooks {
+		h.Fire(ctx, level, msg, fields)
+	}
+}
+
 type Config struct {
 	ops Options
 }
@@ -23,6 +46,16 @@ func New(options ...func(*Options)) (l Logger) {
 	for _, f := range options {
 		f(ops)
 	}
+	ops.sampler = newSampler(ops)
+	ops.levels = newLevelRegistry(ops.level)
+	ops.node = ops.levels.root
+	if ops.caller {
+		ops.callerValuer = caller.Caller(ops.callOptions...)
+	}
+	if ops.slog {
+		l = newSlogLog(ops)
+		return
+	}
 	l = newLogrusLog(ops)
 	return l
 }
@@ -34,3 +67,15 @@ func copyFields(src Fields) Fields {
 	}
 	return dst
 }
+
+// namedOptions options of child logger name
+func namedOptions(ops Options, name string) Options {
+	if ops.name != "" {
+		name = strings.Join([]string{ops.name, name}, ".")
+	}
+	ops.name = name
+	if ops.levels != nil {
+		ops.node = ops.levels.node(name)
+	}
+	return ops
+}
@@ -3,9 +3,7 @@ package log
 import (
 	"context"
 	"fmt"
-	"os"
 
-	"github.com/go-cinch/common/log/caller"
 	"github.com/go-kratos/kratos/v2/log"
 	"github.com/sirupsen/logrus"
 )
@@ -36,114 +34,101 @@ func (l kratosLog) Log(level log.Level, keyvals ...interface{}) (err error) {
 var _ Logger = (*logrusLog)(nil)
 
 type logrusLog struct {
-	log     *logrus.Entry
-	ops     Options
-	fields  Fields
-	valuers Fields
+	entry
+	logger *logrus.Logger
 }
 
 func newLogrusLog(ops *Options) *logrusLog {
 	logger := logrus.New()
-	logger.SetOutput(os.Stdout)
-	logger.SetLevel(loggerToLogrusLogLevel(ops.level))
+	logger.SetOutput(ops.output)
+	// level is checked by enabled, it may be changed at runtime
+	logger.SetLevel(logrus.TraceLevel)
 	logger.SetFormatter(ops.textFormatter)
 	if ops.json {
 		logger.SetFormatter(ops.jsonFormatter)
 	}
-	valuers := ops.valuers
-	if ops.caller {
-		valuers[CallerKey] = caller.Caller(ops.callOptions...)
+	for _, item := range ops.levelOutputs {
+		logger.AddHook(&levelHook{out: item})
 	}
-	entry := logrus.NewEntry(logger)
-	l := logrusLog{
-		log:     entry,
-		ops:     *ops,
-		fields:  make(Fields),
-		valuers: valuers,
+	l := &logrusLog{
+		entry: entry{
+			ops: ops,
+		},
+		logger: logger,
 	}
-	// override default kratos log
+	if ops.sampler != nil {
+		ops.sampler.emit = func(level Level, msg string, fields Fields) {
+			l.with(l.withFields(fields)).output(level, msg)
+		}
+	}
+	ops.logger = overrideKratos(l)
+	return l
+}
+
+// overrideKratos set l as default kratos log
+func overrideKratos(l Logger) log.Logger {
 	k := &kratosLog{
-		log: &l,
+		log: l,
 	}
 	// clear default message key
 	log.DefaultMessageKey = ""
 	log.SetLogger(k)
-	l.ops.logger = k
-	return &l
+	return k
+}
+
+func (l *logrusLog) with(e entry) *logrusLog {
+	return &logrusLog{
+		entry:  e,
+		logger: l.logger,
+	}
 }
 
 func (l *logrusLog) Options() Options {
-	return l.ops
+	return *l.ops
 }
 
 func (l *logrusLog) WithFields(fields Fields) Logger {
-	ns := copyFields(fields)
-	for k, v := range l.fields {
-		ns[k] = v
-	}
-	ll := &logrusLog{
-		log:     l.log,
-		ops:     l.ops,
-		fields:  ns,
-		valuers: l.valuers,
-	}
-	return ll
+	return l.with(l.withFields(fields))
+}
+
+func (l *logrusLog) Named(name string) Logger {
+	return l.with(l.named(name))
 }
 
 func (l *logrusLog) WithContext(ctx context.Context) Logger {
-	ns := copyFields(l.fields)
-	ll := &logrusLog{
-		log:     l.log.WithContext(ctx),
-		ops:     l.ops,
-		fields:  ns,
-		valuers: l.valuers,
-	}
-	return ll
+	return l.with(l.withContext(ctx))
 }
 
 func (l *logrusLog) Log(level Level, args ...interface{}) {
-	ns := l.bindValues()
-	// use new entry avoid race
-	ll := logrus.
-		NewEntry(l.log.Logger).
-		WithContext(l.log.Context)
-	if len(ns) > 0 {
-		ll = ll.WithFields(logrus.Fields(ns))
+	if !l.enabled(level) {
+		return
 	}
-	ll.Log(loggerToLogrusLogLevel(level), fmt.Sprint(args...))
+	msg := fmt.Sprint(args...)
+	if !l.ops.sampled(level, msg) {
+		return
+	}
+	l.output(level, msg)
 }
 
 func (l *logrusLog) Logf(level Level, format string, args ...interface{}) {
-	ns := l.bindValues()
-	ll := logrus.
-		NewEntry(l.log.Logger).
-		WithContext(l.log.Context)
-	if len(ns) > 0 {
-		ll = ll.WithFields(logrus.Fields(ns))
-	}
-	ll.Log(loggerToLogrusLogLevel(level), fmt.Sprintf(format, args...))
-}
-
-func (l *logrusLog) bindValues() Fields {
-	ns1 := copyFields(l.fields)
-	for k, v := range l.valuers {
-		var val interface{}
-		switch v.(type) {
-		case log.Valuer:
-			val = v.(log.Valuer)(l.log.Context)
-		default:
-			val = v
-		}
-		ns1[k] = val
+	if !l.enabled(level) || !l.ops.sampled(level, format) {
+		return
 	}
-	ns2 := make(Fields)
-	for k, v := range ns1 {
-		if str, ok := v.(string); ok && l.ops.skipEmpty && str == "" {
-			continue
-		}
-		ns2[k] = v
+	l.output(level, fmt.Sprintf(format, args...))
+}
+
+func (l *logrusLog) output(level Level, msg string) {
+	ns := l.values(level)
+	msg = l.ops.redactString(msg)
+	// use new entry avoid race, data is copied by logrus so ns can be reused
+	ll := &logrus.Entry{
+		Logger:  l.logger,
+		Data:    logrus.Fields(ns),
+		Context: l.ctx,
 	}
-	return ns2
+	ll.Log(loggerToLogrusLogLevel(level), msg)
+	l.ops.fire(l.ctx, level, msg, ns)
+	putFields(ns)
 }
 
 func loggerToLogrusLogLevel(level Level) logrus.Level {
@@ -165,6 +150,27 @@ func loggerToLogrusLogLevel(level Level) logrus.Level {
 	}
 }
 
+func logrusLevelToLogLevel(level logrus.Level) Level {
+	switch level {
+	case logrus.PanicLevel:
+		return PanicLevel
+	case logrus.TraceLevel:
+		return TraceLevel
+	case logrus.DebugLevel:
+		return DebugLevel
+	case logrus.InfoLevel:
+		return InfoLevel
+	case logrus.WarnLevel:
+		return WarnLevel
+	case logrus.ErrorLevel:
+		return ErrorLevel
+	case logrus.FatalLevel:
+		return FatalLevel
+	default:
+		return InfoLevel
+	}
+}
+
 func kratosLevelToLogLevel(level log.Level) Level {
 	switch level {
 	case log.LevelDebug:
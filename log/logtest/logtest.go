@@ -0,0 +1,245 @@
+package logtest
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-cinch/common/log"
+	klog "github.com/go-kratos/kratos/v2/log"
+)
+
+// Entry a captured log line
+type Entry struct {
+	Time    time.Time
+	Level   log.Level
+	Message string
+	// Fields fields, valuers and extracted fields, caller and logger name are moved to Caller and Logger
+	Fields log.Fields
+	Caller string
+	Logger string
+}
+
+// Logs captured lines, safe for concurrent use
+type Logs struct {
+	lock    sync.RWMutex
+	entries []Entry
+}
+
+func (o *Logs) add(e Entry) {
+	o.lock.Lock()
+	o.entries = append(o.entries, e)
+	o.lock.Unlock()
+}
+
+// Len count of captured lines
+func (o *Logs) Len() int {
+	o.lock.RLock()
+	defer o.lock.RUnlock()
+	return len(o.entries)
+}
+
+// All copy of captured lines
+func (o *Logs) All() []Entry {
+	o.lock.RLock()
+	defer o.lock.RUnlock()
+	rs := make([]Entry, len(o.entries))
+	copy(rs, o.entries)
+	return rs
+}
+
+// TakeAll return captured lines and reset
+func (o *Logs) TakeAll() []Entry {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	rs := o.entries
+	o.entries = nil
+	return rs
+}
+
+// Filter lines match f, returned Logs is a snapshot
+func (o *Logs) Filter(f func(Entry) bool) *Logs {
+	rs := &Logs{}
+	for _, item := range o.All() {
+		if f(item) {
+			rs.entries = append(rs.entries, item)
+		}
+	}
+	return rs
+}
+
+// FilterLevel lines of level
+func (o *Logs) FilterLevel(level log.Level) *Logs {
+	return o.Filter(func(e Entry) bool {
+		return e.Level == level
+	})
+}
+
+// FilterMessage lines with exactly msg
+func (o *Logs) FilterMessage(msg string) *Logs {
+	return o.Filter(func(e Entry) bool {
+		return e.Message == msg
+	})
+}
+
+// FilterMessageSnippet lines contain snippet
+func (o *Logs) FilterMessageSnippet(snippet string) *Logs {
+	return o.Filter(func(e Entry) bool {
+		return strings.Contains(e.Message, snippet)
+	})
+}
+
+// FilterField lines have field k equals v, numbers and errors are compared by text, 1 equals int64(1)
+func (o *Logs) FilterField(k string, v interface{}) *Logs {
+	return o.Filter(func(e Entry) bool {
+		item, ok := e.Fields[k]
+		if !ok {
+			return false
+		}
+		return reflect.DeepEqual(item, v) || fmt.Sprint(item) == fmt.Sprint(v)
+	})
+}
+
+// FilterFieldKey lines have field k
+func (o *Logs) FilterFieldKey(k string) *Logs {
+	return o.Filter(func(e Entry) bool {
+		_, ok := e.Fields[k]
+		return ok
+	})
+}
+
+// New an observer Logger capture lines into Logs, default level is trace,
+// options are the same as log.New except output
+func New(options ...func(*log.Options)) (log.Logger, *Logs) {
+	logs := &Logs{}
+	l := log.New(observerOptions(logs, options)...)
+	return l, logs
+}
+
+// NewWrapper the same as New but return log.Wrapper
+func NewWrapper(options ...func(*log.Options)) (*log.Wrapper, *Logs) {
+	logs := &Logs{}
+	w := log.NewWrapper(observerOptions(logs, options)...)
+	return w, logs
+}
+
+// Swap replace log.DefaultWrapper by an observer, it's restored by t.Cleanup.
+// DefaultWrapper is global, tests use Swap should not run in parallel
+func Swap(t testing.TB, options ...func(*log.Options)) *Logs {
+	t.Helper()
+	prev := log.DefaultWrapper
+	prevKratos := klog.GetLogger()
+	w, logs := NewWrapper(options...)
+	log.DefaultWrapper = w
+	t.Cleanup(func() {
+		log.DefaultWrapper = prev
+		// New override kratos default logger
+		klog.SetLogger(prevKratos)
+	})
+	return logs
+}
+
+func observerOptions(logs *Logs, options []func(*log.Options)) []func(*log.Options) {
+	ops := []func(*log.Options){
+		log.WithLevel(log.TraceLevel),
+	}
+	ops = append(ops, options...)
+	return append(ops, log.WithSlogHandler(&handler{logs: logs}))
+}
+
+var _ slog.Handler = (*handler)(nil)
+
+// handler slog.Handler record lines, level is already checked by log
+type handler struct {
+	logs   *Logs
+	prefix string
+	attrs  []slog.Attr
+}
+
+func (*handler) Enabled(context.Context, slog.Level) bool {
+	return true
+}
+
+func (h *handler) Handle(_ context.Context, r slog.Record) error {
+	e := Entry{
+		Time:    r.Time,
+		Level:   slogLevelToLogLevel(r.Level),
+		Message: r.Message,
+		Fields:  make(log.Fields, r.NumAttrs()+len(h.attrs)),
+	}
+	for _, a := range h.attrs {
+		addAttr(e.Fields, "", a)
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		addAttr(e.Fields, h.prefix, a)
+		return true
+	})
+	if v, ok := e.Fields[log.CallerKey]; ok {
+		e.Caller = fmt.Sprint(v)
+		delete(e.Fields, log.CallerKey)
+	}
+	if v, ok := e.Fields[log.LoggerKey]; ok {
+		e.Logger = fmt.Sprint(v)
+		delete(e.Fields, log.LoggerKey)
+	}
+	h.logs.add(e)
+	return nil
+}
+
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	rs := *h
+	rs.attrs = append([]slog.Attr{}, h.attrs...)
+	for _, a := range attrs {
+		a.Key = h.prefix + a.Key
+		rs.attrs = append(rs.attrs, a)
+	}
+	return &rs
+}
+
+func (h *handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	rs := *h
+	rs.prefix = strings.Join([]string{h.prefix, name, "."}, "")
+	return &rs
+}
+
+func addAttr(fields log.Fields, prefix string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Value.Kind() == slog.KindGroup {
+		p := prefix
+		if a.Key != "" {
+			p = strings.Join([]string{prefix, a.Key, "."}, "")
+		}
+		for _, item := range a.Value.Group() {
+			addAttr(fields, p, item)
+		}
+		return
+	}
+	fields[prefix+a.Key] = a.Value.Any()
+}
+
+func slogLevelToLogLevel(level slog.Level) log.Level {
+	switch {
+	case level >= slog.Level(16):
+		return log.PanicLevel
+	case level >= slog.Level(12):
+		return log.FatalLevel
+	case level >= slog.LevelError:
+		return log.ErrorLevel
+	case level >= slog.LevelWarn:
+		return log.WarnLevel
+	case level >= slog.LevelInfo:
+		return log.InfoLevel
+	case level >= slog.LevelDebug:
+		return log.DebugLevel
+	default:
+		return log.TraceLevel
+	}
+}
@@ -0,0 +1,65 @@
+package logtest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-cinch/common/log"
+)
+
+func TestNew(t *testing.T) {
+	l, logs := New()
+	l.WithFields(log.Fields{"user": "bob", "count": 1}).Logf(log.InfoLevel, "test info %d", 1)
+	l.(log.NamedLogger).Named("worker").Log(log.DebugLevel, "test debug")
+	l.WithFields(log.Fields{log.ErrKey: errors.New("something error")}).Log(log.ErrorLevel, "test error")
+
+	if logs.Len() != 3 {
+		t.Fatalf("expect 3 lines, got %d", logs.Len())
+	}
+	e := logs.All()[0]
+	if e.Level != log.InfoLevel || e.Message != "test info 1" || e.Fields["user"] != "bob" {
+		t.Errorf("unexpected entry %+v", e)
+	}
+	if _, ok := e.Fields[log.CallerKey]; ok {
+		t.Errorf("expect caller moved out of fields, got %v", e.Fields)
+	}
+	if logs.FilterLevel(log.DebugLevel).All()[0].Logger != "worker" {
+		t.Errorf("expect logger name worker")
+	}
+	if logs.FilterField("count", 1).Len() != 1 || logs.FilterField("count", 2).Len() != 0 {
+		t.Errorf("expect count field compared by text")
+	}
+	if logs.FilterField(log.ErrKey, "something error").FilterMessage("test error").Len() != 1 {
+		t.Errorf("expect error line, got %+v", logs.All())
+	}
+	if logs.FilterMessageSnippet("test").Len() != 3 || logs.FilterFieldKey("user").Len() != 1 {
+		t.Errorf("expect filter by snippet and key")
+	}
+	if len(logs.TakeAll()) != 3 || logs.Len() != 0 {
+		t.Errorf("expect logs reset after TakeAll")
+	}
+}
+
+func TestNew_level(t *testing.T) {
+	l, logs := New(log.WithLevel(log.WarnLevel))
+	l.Log(log.InfoLevel, "test info")
+	l.Log(log.WarnLevel, "test warn")
+	if logs.Len() != 1 || logs.All()[0].Level != log.WarnLevel {
+		t.Errorf("expect only warn line, got %+v", logs.All())
+	}
+}
+
+func TestSwap(t *testing.T) {
+	prev := log.DefaultWrapper
+	t.Run("swap", func(t *testing.T) {
+		logs := Swap(t)
+		log.WithContext(context.Background()).WithField("user", "bob").Info("test %s", "swap")
+		if logs.FilterMessage("test swap").FilterField("user", "bob").Len() != 1 {
+			t.Errorf("expect line captured, got %+v", logs.All())
+		}
+	})
+	if log.DefaultWrapper != prev {
+		t.Errorf("expect DefaultWrapper restored")
+	}
+}
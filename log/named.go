@@ -0,0 +1,186 @@
+package log
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+const inheritLevel = -1
+
+// levelNode level of a named logger, inherit from parent if not set
+type levelNode struct {
+	name   string
+	parent *levelNode
+	level  atomic.Int32
+}
+
+func (n *levelNode) get() Level {
+	for p := n; p != nil; p = p.parent {
+		if v := p.level.Load(); v != inheritLevel {
+			return Level(v)
+		}
+	}
+	return InfoLevel
+}
+
+// levelRegistry levels of root and named loggers created from the same New
+type levelRegistry struct {
+	lock  sync.Mutex
+	root  *levelNode
+	nodes map[string]*levelNode
+}
+
+func newLevelRegistry(level Level) *levelRegistry {
+	r := &levelRegistry{
+		root:  &levelNode{},
+		nodes: make(map[string]*levelNode),
+	}
+	r.root.level.Store(int32(level))
+	return r
+}
+
+// node get or create node of name, parents are split by dot
+func (r *levelRegistry) node(name string) *levelNode {
+	if name == "" {
+		return r.root
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.nodeLocked(name)
+}
+
+func (r *levelRegistry) nodeLocked(name string) *levelNode {
+	if n, ok := r.nodes[name]; ok {
+		return n
+	}
+	parent := r.root
+	if i := strings.LastIndex(name, "."); i > 0 {
+		parent = r.nodeLocked(name[:i])
+	}
+	n := &levelNode{
+		name:   name,
+		parent: parent,
+	}
+	n.level.Store(inheritLevel)
+	r.nodes[name] = n
+	return n
+}
+
+// LevelStatus level of a logger, Inherit means level comes from parent
+type LevelStatus struct {
+	Level   string `json:"level"`
+	Inherit bool   `json:"inherit"`
+}
+
+// LevelsStatus levels of root and named loggers
+type LevelsStatus struct {
+	Level   string                 `json:"level"`
+	Loggers map[string]LevelStatus `json:"loggers"`
+}
+
+func (r *levelRegistry) status() (s LevelsStatus) {
+	s.Level = r.root.get().String()
+	s.Loggers = make(map[string]LevelStatus)
+	r.lock.Lock()
+	names := make([]string, 0, len(r.nodes))
+	for name := range r.nodes {
+		names = append(names, name)
+	}
+	r.lock.Unlock()
+	sort.Strings(names)
+	for _, name := range names {
+		n := r.node(name)
+		s.Loggers[name] = LevelStatus{
+			Level:   n.get().String(),
+			Inherit: n.level.Load() == inheritLevel,
+		}
+	}
+	return
+}
+
+// Named child logger, name is joined to parent name by dot and printed as LoggerKey,
+// level is inherited from parent until changed by SetLevel
+func (w *Wrapper) Named(name string) *Wrapper {
+	if l, ok := w.log.(NamedLogger); ok {
+		return &Wrapper{
+			log: l.Named(name),
+		}
+	}
+	// logger without NamedLogger only prints the name
+	return &Wrapper{
+		log: w.log.WithFields(Fields{LoggerKey: name}),
+	}
+}
+
+// SetLevel change level of w at runtime, named loggers without own level follow it
+func (w *Wrapper) SetLevel(level Level) {
+	ops := w.log.Options()
+	if ops.node != nil {
+		ops.node.level.Store(int32(level))
+	}
+}
+
+// ResetLevel named logger inherit level from parent again, no effect on root logger
+func (w *Wrapper) ResetLevel() {
+	ops := w.log.Options()
+	if ops.node != nil && ops.node.parent != nil {
+		ops.node.level.Store(inheritLevel)
+	}
+}
+
+type setLevelReq struct {
+	Name  string `json:"name"`
+	Level string `json:"level"`
+}
+
+// LevelHandler view(GET) or change(PUT/POST) levels of w and its named loggers at runtime.
+// change by query ?name=worker&level=debug or JSON body {"name":"worker","level":"debug"},
+// empty name means w, empty level of named logger means inherit from parent
+func (w *Wrapper) LevelHandler() http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		ops := w.log.Options()
+		if ops.levels == nil {
+			http.Error(rw, "level is not changeable", http.StatusNotImplemented)
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut, http.MethodPost:
+			var req setLevelReq
+			if r.URL.Query().Has("level") {
+				req.Name = r.URL.Query().Get("name")
+				req.Level = r.URL.Query().Get("level")
+			} else if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(rw, err.Error(), http.StatusBadRequest)
+				return
+			}
+			name := req.Name
+			if ops.name != "" && name != "" {
+				name = strings.Join([]string{ops.name, name}, ".")
+			} else if name == "" {
+				name = ops.name
+			}
+			n := ops.levels.node(name)
+			if req.Level == "" && n.parent != nil {
+				n.level.Store(inheritLevel)
+				break
+			}
+			level, ok := levelVal[strings.ToLower(req.Level)]
+			if !ok {
+				http.Error(rw, "invalid level", http.StatusBadRequest)
+				return
+			}
+			n.level.Store(int32(level))
+		default:
+			rw.Header().Set("Allow", "GET, PUT, POST")
+			http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		rw.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(rw).Encode(ops.levels.status())
+	})
+}
@@ -0,0 +1,90 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWrapper_Named(t *testing.T) {
+	for _, flag := range []bool{false, true} {
+		var buf bytes.Buffer
+		w := NewWrapper(WithOutput(&buf), WithCaller(false), WithSlog(flag))
+		worker := w.Named("worker")
+		asynq := worker.Named("asynq")
+
+		worker.Debug("debug 1")
+		w.SetLevel(DebugLevel)
+		asynq.Debug("debug 2")
+		worker.SetLevel(WarnLevel)
+		worker.Info("info 3")
+		asynq.Info("info 4")
+		w.Info("info 5")
+		worker.ResetLevel()
+		asynq.Info("info 6")
+
+		out := buf.String()
+		for msg, expect := range map[string]bool{"debug 1": false, "debug 2": true, "info 3": false, "info 4": false, "info 5": true, "info 6": true} {
+			if strings.Contains(out, msg) != expect {
+				t.Errorf("slog %v: expect %s printed %v, got %s", flag, msg, expect, out)
+			}
+		}
+		if !strings.Contains(out, "logger=worker.asynq") {
+			t.Errorf("expect logger name printed, got %s", out)
+		}
+	}
+}
+
+// unnamedLogger custom logger without NamedLogger
+type unnamedLogger struct {
+	Logger
+}
+
+func TestWrapper_Named_unnamedLogger(t *testing.T) {
+	var buf bytes.Buffer
+	w := &Wrapper{log: unnamedLogger{New(WithOutput(&buf), WithCaller(false))}}
+	w.Named("worker").Info("info 1")
+	if !strings.Contains(buf.String(), "logger=worker") {
+		t.Errorf("expect logger name printed, got %s", buf.String())
+	}
+}
+
+func TestWrapper_LevelHandler(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWrapper(WithOutput(&buf), WithCaller(false))
+	worker := w.Named("worker")
+	h := w.LevelHandler()
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/log/level?name=worker&level=debug", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expect 200, got %d %s", rec.Code, rec.Body.String())
+	}
+	var s LevelsStatus
+	err := json.Unmarshal(rec.Body.Bytes(), &s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Level != "info" || s.Loggers["worker"].Level != "debug" || s.Loggers["worker"].Inherit {
+		t.Errorf("unexpected status %+v", s)
+	}
+	worker.Debug("worker debug")
+	if !strings.Contains(buf.String(), "worker debug") {
+		t.Errorf("expect worker debug printed")
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/log/level", strings.NewReader(`{"name":"worker"}`)))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"inherit":true`) {
+		t.Errorf("expect worker inherit level, got %d %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/log/level?level=verbose", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expect 400, got %d", rec.Code)
+	}
+}
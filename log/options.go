@@ -1,6 +1,12 @@
 package log
 
 import (
+	"io"
+	"log/slog"
+	"os"
+	"regexp"
+	"time"
+
 	"github.com/go-cinch/common/log/caller"
 	"github.com/go-kratos/kratos/v2/log"
 	"github.com/sirupsen/logrus"
@@ -11,18 +17,46 @@ type Options struct {
 	logger        log.Logger
 	caller        bool
 	callOptions   []func(*caller.Options)
+	callerValuer  log.Valuer
 	text          bool
 	textFormatter *logrus.TextFormatter
 	json          bool
 	jsonFormatter *logrus.JSONFormatter
 	skipEmpty     bool
 	valuers       Fields
+	output        io.Writer
+	levelOutputs  []levelOutput
+	slog          bool
+	slogHandler   slog.Handler
+	redactor      *Redactor
+	extractors    []Extractor
+	hooks         []Hook
+	errorStack    *bool
+	// sampling
+	sampleFirst      int
+	sampleThereafter int
+	sampleInterval   time.Duration
+	rateLimits       map[Level][2]int
+	sampler          *sampler
+	// runtime level
+	name   string
+	levels *levelRegistry
+	node   *levelNode
 }
 
+// Level current level, may be changed at runtime by SetLevel
 func (o Options) Level() Level {
+	if o.node != nil {
+		return o.node.get()
+	}
 	return o.level
 }
 
+// Name name of logger, empty for root
+func (o Options) Name() string {
+	return o.name
+}
+
 func (o Options) Logger() log.Logger {
 	return o.logger
 }
@@ -80,6 +114,105 @@ func WithSkipEmpty(flag bool) func(*Options) {
 	}
 }
 
+// WithOutput change log output, default os.Stdout, multiple writers are fan out by MultiWriter
+func WithOutput(w ...io.Writer) func(*Options) {
+	return func(options *Options) {
+		if len(w) == 1 {
+			getOptionsOrSetDefault(options).output = w[0]
+			return
+		}
+		getOptionsOrSetDefault(options).output = MultiWriter(w...)
+	}
+}
+
+// WithLevelOutput also write lines at level or more severe to w, such as errors to a separate file
+func WithLevelOutput(level Level, w io.Writer) func(*Options) {
+	return func(options *Options) {
+		getOptionsOrSetDefault(options).levelOutputs = append(getOptionsOrSetDefault(options).levelOutputs, levelOutput{level: level, w: w})
+	}
+}
+
+// WithSlog use log/slog as backend instead of logrus, text or JSON format is kept
+func WithSlog(flag bool) func(*Options) {
+	return func(options *Options) {
+		getOptionsOrSetDefault(options).slog = flag
+	}
+}
+
+// WithSlogHandler use log/slog backend with an external handler, valuers and caller are still added
+func WithSlogHandler(h slog.Handler) func(*Options) {
+	return func(options *Options) {
+		ops := getOptionsOrSetDefault(options)
+		ops.slog = h != nil
+		ops.slogHandler = h
+	}
+}
+
+// WithRedactor mask sensitive fields and message before output
+func WithRedactor(r *Redactor) func(*Options) {
+	return func(options *Options) {
+		getOptionsOrSetDefault(options).redactor = r
+	}
+}
+
+// WithSampling print the first lines of each message template(format or message) and level in an interval,
+// then every thereafter line, 0 thereafter means drop all. dropped counts are summarized by a warn line
+func WithSampling(first, thereafter int) func(*Options) {
+	return func(options *Options) {
+		ops := getOptionsOrSetDefault(options)
+		ops.sampleFirst = first
+		ops.sampleThereafter = thereafter
+	}
+}
+
+// WithSamplingInterval sampling counter reset and summary interval, default 1000 milli second
+func WithSamplingInterval(milli int) func(*Options) {
+	return func(options *Options) {
+		getOptionsOrSetDefault(options).sampleInterval = time.Duration(milli) * time.Millisecond
+	}
+}
+
+// WithRateLimit token bucket of level, at most rate lines per second with burst
+func WithRateLimit(level Level, rate, burst int) func(*Options) {
+	return func(options *Options) {
+		ops := getOptionsOrSetDefault(options)
+		if ops.rateLimits == nil {
+			ops.rateLimits = make(map[Level][2]int)
+		}
+		ops.rateLimits[level] = [2]int{rate, burst}
+	}
+}
+
+// WithExtractor add fields extracted from context of each line, such as tenant.LogExtractor/jwt.LogExtractor/otel.TraceExtractor
+func WithExtractor(fun ...Extractor) func(*Options) {
+	return func(options *Options) {
+		getOptionsOrSetDefault(options).extractors = append(getOptionsOrSetDefault(options).extractors, fun...)
+	}
+}
+
+// WithRequestID print request_id in context, see RequestIDFromContext
+func WithRequestID(flag bool) func(*Options) {
+	return func(options *Options) {
+		if flag {
+			getOptionsOrSetDefault(options).extractors = append(getOptionsOrSetDefault(options).extractors, RequestIDExtractor())
+		}
+	}
+}
+
+// WithHook also pass each line to hooks, such as log/otel.NewHook
+func WithHook(h ...Hook) func(*Options) {
+	return func(options *Options) {
+		getOptionsOrSetDefault(options).hooks = append(getOptionsOrSetDefault(options).hooks, h...)
+	}
+}
+
+// WithErrorStack print stack of pkg/errors at error level and above, default true for JSON format and false for text format
+func WithErrorStack(flag bool) func(*Options) {
+	return func(options *Options) {
+		getOptionsOrSetDefault(options).errorStack = &flag
+	}
+}
+
 func getOptionsOrSetDefault(options *Options) *Options {
 	if options == nil {
 		return &Options{
@@ -101,6 +234,146 @@ func getOptionsOrSetDefault(options *Options) *Options {
 			},
 			skipEmpty: true,
 			valuers:   make(Fields),
+			output:    os.Stdout,
+			// sampling
+			sampleInterval: time.Second,
+		}
+	}
+	return options
+}
+
+type RotateOptions struct {
+	maxSize    int64
+	interval   time.Duration
+	maxAge     time.Duration
+	maxBackups int
+	compress   bool
+	localTime  bool
+	onError    func(error)
+}
+
+// WithRotateMaxSize rotate when file size reach mb, default 100, 0 means no size limit
+func WithRotateMaxSize(mb int) func(*RotateOptions) {
+	return func(options *RotateOptions) {
+		getRotateOptionsOrSetDefault(options).maxSize = int64(mb) * 1024 * 1024
+	}
+}
+
+// WithRotateInterval rotate every minute, aligned to clock, e.g. 1440 rotates at midnight, default 0(disabled)
+func WithRotateInterval(minute int) func(*RotateOptions) {
+	return func(options *RotateOptions) {
+		getRotateOptionsOrSetDefault(options).interval = time.Duration(minute) * time.Minute
+	}
+}
+
+// WithRotateMaxAge remove backups older than day, default 0(keep all)
+func WithRotateMaxAge(day int) func(*RotateOptions) {
+	return func(options *RotateOptions) {
+		getRotateOptionsOrSetDefault(options).maxAge = time.Duration(day) * 24 * time.Hour
+	}
+}
+
+// WithRotateMaxBackups keep at most count backups, default 0(keep all)
+func WithRotateMaxBackups(count int) func(*RotateOptions) {
+	return func(options *RotateOptions) {
+		getRotateOptionsOrSetDefault(options).maxBackups = count
+	}
+}
+
+// WithRotateCompress gzip backups, default false
+func WithRotateCompress(flag bool) func(*RotateOptions) {
+	return func(options *RotateOptions) {
+		getRotateOptionsOrSetDefault(options).compress = flag
+	}
+}
+
+// WithRotateLocalTime use local time for interval and backup name, default UTC
+func WithRotateLocalTime(flag bool) func(*RotateOptions) {
+	return func(options *RotateOptions) {
+		getRotateOptionsOrSetDefault(options).localTime = flag
+	}
+}
+
+// WithRotateErrorHandler called when compress or remove backup failed in background,
+// or rotate failed while writing(write goes on to current file), default ignore
+func WithRotateErrorHandler(f func(err error)) func(*RotateOptions) {
+	return func(options *RotateOptions) {
+		getRotateOptionsOrSetDefault(options).onError = f
+	}
+}
+
+func getRotateOptionsOrSetDefault(options *RotateOptions) *RotateOptions {
+	if options == nil {
+		return &RotateOptions{
+			maxSize: 100 * 1024 * 1024,
+		}
+	}
+	return options
+}
+
+type RedactOptions struct {
+	keys   []string
+	values []*regexp.Regexp
+	mask   string
+}
+
+// WithRedactKeys add key rules(case-insensitive, * matches any characters), default DefaultRedactKeys
+func WithRedactKeys(keys ...string) func(*RedactOptions) {
+	return func(options *RedactOptions) {
+		getRedactOptionsOrSetDefault(options).keys = append(getRedactOptionsOrSetDefault(options).keys, keys...)
+	}
+}
+
+// WithRedactValues add value rules, such as RedactCardNumber/RedactEmail/RedactPhone
+func WithRedactValues(values ...*regexp.Regexp) func(*RedactOptions) {
+	return func(options *RedactOptions) {
+		getRedactOptionsOrSetDefault(options).values = append(getRedactOptionsOrSetDefault(options).values, values...)
+	}
+}
+
+// WithRedactMask replacement of sensitive value, default ***
+func WithRedactMask(mask string) func(*RedactOptions) {
+	return func(options *RedactOptions) {
+		getRedactOptionsOrSetDefault(options).mask = mask
+	}
+}
+
+func getRedactOptionsOrSetDefault(options *RedactOptions) *RedactOptions {
+	if options == nil {
+		return &RedactOptions{
+			keys: append([]string{}, DefaultRedactKeys...),
+			mask: "***",
+		}
+	}
+	return options
+}
+
+type AsyncOptions struct {
+	size   int
+	policy OverflowPolicy
+}
+
+// WithAsyncSize max buffered lines, default 1024
+func WithAsyncSize(size int) func(*AsyncOptions) {
+	return func(options *AsyncOptions) {
+		if size > 0 {
+			getAsyncOptionsOrSetDefault(options).size = size
+		}
+	}
+}
+
+// WithAsyncOverflow policy when buffer is full, default OverflowBlock
+func WithAsyncOverflow(policy OverflowPolicy) func(*AsyncOptions) {
+	return func(options *AsyncOptions) {
+		getAsyncOptionsOrSetDefault(options).policy = policy
+	}
+}
+
+func getAsyncOptionsOrSetDefault(options *AsyncOptions) *AsyncOptions {
+	if options == nil {
+		return &AsyncOptions{
+			size:   1024,
+			policy: OverflowBlock,
 		}
 	}
 	return options
@@ -0,0 +1,170 @@
+package otel
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-cinch/common/log"
+	otellog "go.opentelemetry.io/otel/log"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// Scope instrumentation scope name of log records
+	Scope = "github.com/go-cinch/common/log"
+
+	TraceIDKey = "trace_id"
+	SpanIDKey  = "span_id"
+)
+
+var _ log.Hook = (*Hook)(nil)
+
+// Hook emit each line as OpenTelemetry log record, fields are attributes and span context of ctx is linked by sdk
+type Hook struct {
+	provider otellog.LoggerProvider
+	logger   otellog.Logger
+}
+
+// NewHook emit lines by provider, such as sdk/log LoggerProvider
+func NewHook(provider otellog.LoggerProvider) *Hook {
+	return &Hook{
+		provider: provider,
+		logger:   provider.Logger(Scope),
+	}
+}
+
+func (h *Hook) Fire(ctx context.Context, level log.Level, msg string, fields log.Fields) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	now := time.Now()
+	var r otellog.Record
+	r.SetTimestamp(now)
+	r.SetObservedTimestamp(now)
+	r.SetSeverity(logLevelToOTelSeverity(level))
+	r.SetSeverityText(level.String())
+	r.SetBody(otellog.StringValue(msg))
+	for k, v := range fields {
+		r.AddAttributes(otellog.KeyValue{Key: k, Value: otelValue(v)})
+	}
+	h.logger.Emit(ctx, r)
+}
+
+// Flush force flush provider if it supports, so records are exported before log.Wrapper.Fatal exits
+func (h *Hook) Flush() (err error) {
+	if f, ok := h.provider.(interface {
+		ForceFlush(ctx context.Context) error
+	}); ok {
+		err = f.ForceFlush(context.Background())
+	}
+	return
+}
+
+// WithOTel also emit each line as OpenTelemetry log record by provider, see NewHook
+func WithOTel(provider otellog.LoggerProvider) func(*log.Options) {
+	return func(options *log.Options) {
+		if provider != nil {
+			log.WithHook(NewHook(provider))(options)
+		}
+	}
+}
+
+// TraceExtractor trace_id and span_id of OpenTelemetry span
+func TraceExtractor() log.Extractor {
+	return func(ctx context.Context) log.Fields {
+		sc := trace.SpanContextFromContext(ctx)
+		if !sc.IsValid() {
+			return nil
+		}
+		return log.Fields{
+			TraceIDKey: sc.TraceID().String(),
+			SpanIDKey:  sc.SpanID().String(),
+		}
+	}
+}
+
+// WithTraceContext print trace_id and span_id of OpenTelemetry span in context
+func WithTraceContext(flag bool) func(*log.Options) {
+	return func(options *log.Options) {
+		if flag {
+			log.WithExtractor(TraceExtractor())(options)
+		}
+	}
+}
+
+func otelValue(v interface{}) otellog.Value {
+	switch val := v.(type) {
+	case nil:
+		return otellog.Value{}
+	case string:
+		return otellog.StringValue(val)
+	case bool:
+		return otellog.BoolValue(val)
+	case int:
+		return otellog.IntValue(val)
+	case int8:
+		return otellog.Int64Value(int64(val))
+	case int16:
+		return otellog.Int64Value(int64(val))
+	case int32:
+		return otellog.Int64Value(int64(val))
+	case int64:
+		return otellog.Int64Value(val)
+	case uint8:
+		return otellog.Int64Value(int64(val))
+	case uint16:
+		return otellog.Int64Value(int64(val))
+	case uint32:
+		return otellog.Int64Value(int64(val))
+	case float32:
+		return otellog.Float64Value(float64(val))
+	case float64:
+		return otellog.Float64Value(val)
+	case []byte:
+		return otellog.BytesValue(val)
+	case error:
+		return otellog.StringValue(val.Error())
+	case fmt.Stringer:
+		return otellog.StringValue(val.String())
+	case log.ErrorView:
+		kvs := []otellog.KeyValue{
+			otellog.String("message", val.Message),
+			otellog.String("type", val.Type),
+		}
+		if val.Reason != "" {
+			kvs = append(kvs, otellog.String("reason", val.Reason), otellog.Int64("code", int64(val.Code)))
+		}
+		if val.Stack != "" {
+			kvs = append(kvs, otellog.String("stack", val.Stack))
+		}
+		return otellog.MapValue(kvs...)
+	case log.Fields:
+		kvs := make([]otellog.KeyValue, 0, len(val))
+		for k, item := range val {
+			kvs = append(kvs, otellog.KeyValue{Key: k, Value: otelValue(item)})
+		}
+		return otellog.MapValue(kvs...)
+	default:
+		return otellog.StringValue(fmt.Sprintf("%+v", v))
+	}
+}
+
+func logLevelToOTelSeverity(level log.Level) otellog.Severity {
+	switch level {
+	case log.PanicLevel:
+		return otellog.SeverityFatal4
+	case log.FatalLevel:
+		return otellog.SeverityFatal
+	case log.ErrorLevel:
+		return otellog.SeverityError
+	case log.WarnLevel:
+		return otellog.SeverityWarn
+	case log.DebugLevel:
+		return otellog.SeverityDebug
+	case log.TraceLevel:
+		return otellog.SeverityTrace
+	default:
+		return otellog.SeverityInfo
+	}
+}
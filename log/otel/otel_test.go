@@ -0,0 +1,130 @@
+package otel
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/go-cinch/common/log"
+	otellog "go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// memoryExporter keep exported records in memory
+type memoryExporter struct {
+	lock    sync.Mutex
+	records []sdklog.Record
+}
+
+func (e *memoryExporter) Export(_ context.Context, records []sdklog.Record) error {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	for _, item := range records {
+		e.records = append(e.records, item.Clone())
+	}
+	return nil
+}
+
+func (*memoryExporter) Shutdown(context.Context) error {
+	return nil
+}
+
+func (*memoryExporter) ForceFlush(context.Context) error {
+	return nil
+}
+
+func TestWithOTel(t *testing.T) {
+	for _, flag := range []bool{false, true} {
+		exporter := &memoryExporter{}
+		provider := sdklog.NewLoggerProvider(sdklog.WithProcessor(sdklog.NewSimpleProcessor(exporter)))
+		var buf bytes.Buffer
+		w := log.NewWrapper(
+			log.WithOutput(&buf),
+			log.WithSlog(flag),
+			log.WithCaller(false),
+			WithOTel(provider),
+		)
+		sc := trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID: trace.TraceID{1},
+			SpanID:  trace.SpanID{2},
+		})
+		ctx := trace.ContextWithSpanContext(context.Background(), sc)
+
+		w.Debug("not emit since info level")
+		w.WithContext(ctx).WithError(errors.New("something error")).WithField("count", 2).Warn("test warn %d", 1)
+
+		if len(exporter.records) != 1 {
+			t.Fatalf("expect 1 record, got %d", len(exporter.records))
+		}
+		r := exporter.records[0]
+		if r.Body().AsString() != "test warn 1" || r.Severity() != otellog.SeverityWarn || r.SeverityText() != "warn" {
+			t.Errorf("unexpected record %v %v %s", r.Body(), r.Severity(), r.SeverityText())
+		}
+		if r.TraceID() != sc.TraceID() || r.SpanID() != sc.SpanID() {
+			t.Errorf("expect span context linked, got %s %s", r.TraceID(), r.SpanID())
+		}
+		attrs := make(map[string]otellog.Value)
+		r.WalkAttributes(func(kv otellog.KeyValue) bool {
+			attrs[kv.Key] = kv.Value
+			return true
+		})
+		if attrs[log.ErrKey].AsString() != "something error" || attrs["count"].AsInt64() != 2 {
+			t.Errorf("unexpected attributes %v", attrs)
+		}
+		if buf.Len() == 0 {
+			t.Errorf("expect output is kept")
+		}
+	}
+}
+
+func TestWithTraceContext(t *testing.T) {
+	var buf bytes.Buffer
+	w := log.NewWrapper(
+		log.WithOutput(&buf),
+		log.WithCaller(false),
+		WithTraceContext(true),
+	)
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{1},
+		SpanID:  trace.SpanID{2},
+	})
+	w.WithContext(trace.ContextWithSpanContext(context.Background(), sc)).Info("with span")
+	w.WithContext(context.Background()).Info("without span")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expect 2 lines, got %q", buf.String())
+	}
+	for _, item := range []string{"trace_id=" + sc.TraceID().String(), "span_id=" + sc.SpanID().String()} {
+		if !strings.Contains(lines[0], item) {
+			t.Errorf("expect %s in %s", item, lines[0])
+		}
+	}
+	if strings.Contains(lines[1], TraceIDKey) {
+		t.Errorf("expect no trace fields, got %s", lines[1])
+	}
+}
+
+type flushExporter struct {
+	memoryExporter
+	flushed int
+}
+
+func (e *flushExporter) ForceFlush(context.Context) error {
+	e.flushed++
+	return nil
+}
+
+func TestHook_Flush(t *testing.T) {
+	exporter := &flushExporter{}
+	provider := sdklog.NewLoggerProvider(sdklog.WithProcessor(sdklog.NewBatchProcessor(exporter)))
+	w := log.NewWrapper(log.WithOutput(&bytes.Buffer{}), WithOTel(provider))
+	w.Info("buffered by batch processor")
+	if err := w.Flush(); err != nil || exporter.flushed != 1 || len(exporter.records) != 1 {
+		t.Errorf("expect record exported by flush, got %d %d %v", exporter.flushed, len(exporter.records), err)
+	}
+}
@@ -0,0 +1,72 @@
+package log
+
+import (
+	"io"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// levelOutput write entries at level or more severe to w
+type levelOutput struct {
+	level Level
+	w     io.Writer
+}
+
+// levelHook copy formatted entry to another writer, used for per-level routing
+type levelHook struct {
+	lock sync.Mutex
+	out  levelOutput
+}
+
+func (h *levelHook) Levels() []logrus.Level {
+	levels := make([]logrus.Level, 0, len(logrus.AllLevels))
+	for _, level := range logrus.AllLevels {
+		if h.out.level.Enabled(logrusLevelToLogLevel(level)) {
+			levels = append(levels, level)
+		}
+	}
+	return levels
+}
+
+func (h *levelHook) Fire(entry *logrus.Entry) (err error) {
+	serialized, err := entry.Logger.Formatter.Format(entry)
+	if err != nil {
+		return
+	}
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	_, err = h.out.w.Write(serialized)
+	return
+}
+
+type multiWriter struct {
+	ws []io.Writer
+}
+
+// MultiWriter fan out each write to all writers,
+// unlike io.MultiWriter, a failed writer does not stop the others, the first error is returned
+func MultiWriter(ws ...io.Writer) io.Writer {
+	all := make([]io.Writer, 0, len(ws))
+	for _, w := range ws {
+		if mw, ok := w.(*multiWriter); ok {
+			all = append(all, mw.ws...)
+			continue
+		}
+		if w != nil {
+			all = append(all, w)
+		}
+	}
+	return &multiWriter{ws: all}
+}
+
+func (m *multiWriter) Write(p []byte) (n int, err error) {
+	for _, w := range m.ws {
+		_, e := w.Write(p)
+		if e != nil && err == nil {
+			err = e
+		}
+	}
+	n = len(p)
+	return
+}
@@ -0,0 +1,242 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+type errWriter struct{}
+
+func (errWriter) Write([]byte) (int, error) {
+	return 0, errors.New("broken")
+}
+
+func TestWithOutput(t *testing.T) {
+	var all, errs, other bytes.Buffer
+	w := NewWrapper(
+		WithOutput(&all, errWriter{}, &other),
+		WithLevelOutput(ErrorLevel, &errs),
+		WithCaller(false),
+	)
+	w.Info("test info")
+	w.Warn("test warn")
+	w.Error("test error")
+
+	if n := strings.Count(all.String(), "\n"); n != 3 {
+		t.Errorf("expect 3 lines in output, got %d", n)
+	}
+	if all.String() != other.String() {
+		t.Errorf("expect broken writer not stop fan out")
+	}
+	if !strings.Contains(errs.String(), "test error") || strings.Contains(errs.String(), "test warn") {
+		t.Errorf("expect only error in level output, got %s", errs.String())
+	}
+}
+
+func TestRotateFile(t *testing.T) {
+	dir := t.TempDir()
+	rf, err := NewRotateFile(filepath.Join(dir, "app.log"), WithRotateMaxBackups(2), WithRotateCompress(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rf.Close()
+	rf.ops.maxSize = 10
+
+	for i := 0; i < 4; i++ {
+		_, err = rf.Write([]byte("0123456789"))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, err = rf.Write(make([]byte, 11))
+	if err == nil {
+		t.Errorf("expect write exceeds max size failed")
+	}
+
+	var gz []string
+	for i := 0; i < 100; i++ {
+		gz, _ = filepath.Glob(filepath.Join(dir, "app-*.log.gz"))
+		plain, _ := filepath.Glob(filepath.Join(dir, "app-*.log"))
+		if len(gz) == 2 && len(plain) == 0 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(gz) != 2 {
+		t.Errorf("expect 2 compressed backups, got %v", gz)
+	}
+	info, err := os.Stat(filepath.Join(dir, "app.log"))
+	if err != nil || info.Size() != 10 {
+		t.Errorf("expect current file has 10 bytes, got %v", err)
+	}
+}
+
+func TestRotateFile_sameTime(t *testing.T) {
+	dir := t.TempDir()
+	rf, err := NewRotateFile(filepath.Join(dir, "app.log"), WithRotateMaxBackups(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rf.Close()
+	now := rf.now()
+	for _, item := range []string{"1", "2", "3"} {
+		_, _ = rf.Write([]byte(item))
+		rf.lock.Lock()
+		err = rf.rotate(now)
+		rf.lock.Unlock()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	rf.millRun()
+	backups, _ := filepath.Glob(filepath.Join(dir, "app-*.log"))
+	sort.Strings(backups)
+	if len(backups) != 2 {
+		t.Fatalf("expect 2 backups, got %v", backups)
+	}
+	// the oldest one without sequence is removed
+	for i, item := range []string{"2", "3"} {
+		b, _ := os.ReadFile(backups[i])
+		if string(b) != item {
+			t.Errorf("expect backup %s has %s, got %s", backups[i], item, b)
+		}
+	}
+}
+
+func TestRotateFile_renameFailed(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "app.log")
+	var errs []error
+	rf, err := NewRotateFile(filename, WithRotateErrorHandler(func(err error) {
+		errs = append(errs, err)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rf.ops.maxSize = 10
+	_, err = rf.Write([]byte("0123456789"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// rename fails since current file is removed
+	err = os.Remove(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = rf.Write([]byte("abc"))
+	if err != nil {
+		t.Fatalf("expect write after rotate failed, got %v", err)
+	}
+	if len(errs) != 1 {
+		t.Errorf("expect rotate error reported, got %v", errs)
+	}
+	b, _ := os.ReadFile(filename)
+	if string(b) != "abc" {
+		t.Errorf("expect current file reopened with abc, got %s", b)
+	}
+	_ = rf.Close()
+	if _, err = rf.Write([]byte("abc")); !errors.Is(err, os.ErrClosed) {
+		t.Errorf("expect closed file not reopened, got %v", err)
+	}
+}
+
+func TestRotateFile_maxAgeLocalTime(t *testing.T) {
+	local := time.Local
+	time.Local = time.FixedZone("UTC+12", 12*3600)
+	defer func() {
+		time.Local = local
+	}()
+	dir := t.TempDir()
+	old := filepath.Join(dir, strings.Join([]string{"app-", time.Now().Add(-25 * time.Hour).Format(rotateTimeFormat), ".log"}, ""))
+	err := os.WriteFile(old, []byte("1"), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var errs []error
+	rf, err := NewRotateFile(
+		filepath.Join(dir, "app.log"),
+		WithRotateLocalTime(true),
+		WithRotateMaxAge(1),
+		WithRotateErrorHandler(func(err error) {
+			errs = append(errs, err)
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rf.Close()
+	if _, err = os.Stat(old); !os.IsNotExist(err) || len(errs) > 0 {
+		t.Errorf("expect backup older than max age removed, got %v %v", err, errs)
+	}
+}
+
+func TestRotateFile_interval(t *testing.T) {
+	dir := t.TempDir()
+	rf, err := NewRotateFile(filepath.Join(dir, "app.log"), WithRotateInterval(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rf.Close()
+	if next := rf.next; next.Second() != 0 || next.Nanosecond() != 0 || !next.After(time.Now()) {
+		t.Errorf("expect next rotate at next minute, got %v", next)
+	}
+	_, _ = rf.Write([]byte("1"))
+	rf.next = time.Now().Add(-time.Second)
+	_, _ = rf.Write([]byte("2"))
+	backups, _ := filepath.Glob(filepath.Join(dir, "app-*.log"))
+	if len(backups) != 1 {
+		t.Errorf("expect 1 backup, got %v", backups)
+	}
+}
+
+type memoryHook struct {
+	msgs    []string
+	fields  []Fields
+	flushed int
+}
+
+func (h *memoryHook) Fire(_ context.Context, level Level, msg string, fields Fields) {
+	fs := make(Fields, len(fields))
+	for k, v := range fields {
+		fs[k] = v
+	}
+	h.msgs = append(h.msgs, level.String()+" "+msg)
+	h.fields = append(h.fields, fs)
+}
+
+func (h *memoryHook) Flush() error {
+	h.flushed++
+	return nil
+}
+
+func TestWithHook(t *testing.T) {
+	for _, flag := range []bool{false, true} {
+		h := &memoryHook{}
+		var buf bytes.Buffer
+		w := NewWrapper(
+			WithOutput(&buf),
+			WithSlog(flag),
+			WithCaller(false),
+			WithHook(h),
+		)
+		w.Debug("not fired since info level")
+		w.WithError(errors.New("something error")).Warn("test warn")
+
+		if len(h.msgs) != 1 || h.msgs[0] != "warn test warn" || h.fields[0][ErrKey] != "something error" {
+			t.Errorf("expect 1 line fired, got %v %v", h.msgs, h.fields)
+		}
+		if buf.Len() == 0 {
+			t.Errorf("expect output is kept")
+		}
+		if err := w.Flush(); err != nil || h.flushed != 1 {
+			t.Errorf("expect hook flushed, got %d %v", h.flushed, err)
+		}
+	}
+}
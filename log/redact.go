@@ -0,0 +1,281 @@
+package log
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// RedactTag struct field with `log:"redact"` is always masked
+const RedactTag = "redact"
+
+var (
+	// DefaultRedactKeys key rules of a default Redactor, * matches any characters
+	DefaultRedactKeys = []string{"password", "passwd", "secret", "authorization", "*token*"}
+	// RedactCardNumber bank card number
+	RedactCardNumber = regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`)
+	// RedactEmail email address
+	RedactEmail = regexp.MustCompile(`[a-zA-Z0-9._%+\-]+@[a-zA-Z0-9.\-]+\.[a-zA-Z]{2,}`)
+	// RedactPhone mainland china mobile phone number
+	RedactPhone = regexp.MustCompile(`\b1[3-9]\d{9}\b`)
+)
+
+const redactMaxDepth = 8
+
+// Redactor mask sensitive fields and message before output:
+// values of fields whose key matches a key rule,
+// key=value/key:value pairs inside strings whose key matches a key rule,
+// substrings match a value rule,
+// struct fields with `log:"redact"` tag or name matches a key rule
+type Redactor struct {
+	ops   RedactOptions
+	keys  *regexp.Regexp
+	pairs *regexp.Regexp
+	types sync.Map
+}
+
+// NewRedactor create Redactor with DefaultRedactKeys and given rules
+func NewRedactor(options ...func(*RedactOptions)) *Redactor {
+	ops := getRedactOptionsOrSetDefault(nil)
+	for _, f := range options {
+		f(ops)
+	}
+	r := &Redactor{
+		ops: *ops,
+	}
+	if len(ops.keys) > 0 {
+		exact := make([]string, 0, len(ops.keys))
+		inner := make([]string, 0, len(ops.keys))
+		for _, item := range ops.keys {
+			exact = append(exact, globToRegexp(item, ".*"))
+			inner = append(inner, globToRegexp(item, `[\w\-]*`))
+		}
+		r.keys = regexp.MustCompile(strings.Join([]string{`(?i)^(?:`, strings.Join(exact, "|"), `)$`}, ""))
+		r.pairs = regexp.MustCompile(strings.Join([]string{
+			`(?i)("?\b(?:`, strings.Join(inner, "|"), `)"?\s*[:=]\s*)`,
+			`("(?:[^"\\]|\\.)*"|(?:bearer\s+|basic\s+)?[^\s,;&}\])"]+)`,
+		}, ""))
+	}
+	return r
+}
+
+func globToRegexp(glob, any string) string {
+	arr := strings.Split(glob, "*")
+	for i, item := range arr {
+		arr[i] = regexp.QuoteMeta(item)
+	}
+	return strings.Join(arr, any)
+}
+
+// MatchKey key matches one of the key rules
+func (r *Redactor) MatchKey(key string) bool {
+	return r.keys != nil && r.keys.MatchString(key)
+}
+
+// String mask key/value pairs and value rules in s
+func (r *Redactor) String(s string) string {
+	if r.pairs != nil {
+		s = r.pairs.ReplaceAllStringFunc(s, func(m string) string {
+			sub := r.pairs.FindStringSubmatch(m)
+			if strings.HasPrefix(sub[2], `"`) {
+				return strings.Join([]string{sub[1], `"`, r.ops.mask, `"`}, "")
+			}
+			return strings.Join([]string{sub[1], r.ops.mask}, "")
+		})
+	}
+	for _, item := range r.ops.values {
+		s = item.ReplaceAllString(s, r.ops.mask)
+	}
+	return s
+}
+
+// Fields return a copy of fields with sensitive values masked
+func (r *Redactor) Fields(fields Fields) Fields {
+	ns := make(Fields, len(fields))
+	for k, v := range fields {
+		if r.MatchKey(k) {
+			ns[k] = r.ops.mask
+			continue
+		}
+		ns[k] = r.Value(v)
+	}
+	return ns
+}
+
+// Value return v or a masked copy of v, v is never modified
+func (r *Redactor) Value(v interface{}) interface{} {
+	switch val := v.(type) {
+	case nil:
+		return nil
+	case string:
+		return r.String(val)
+	case error:
+		s := val.Error()
+		if rs := r.String(s); rs != s {
+			return rs
+		}
+		return v
+	case Fields:
+		return r.Fields(val)
+	}
+	rv := reflect.ValueOf(v)
+	if nv, ok := r.value(rv, 0); ok {
+		return nv.Interface()
+	}
+	return v
+}
+
+// value return a masked copy of v and true, or false if nothing changed
+func (r *Redactor) value(v reflect.Value, depth int) (nv reflect.Value, changed bool) {
+	if depth > redactMaxDepth {
+		return
+	}
+	switch v.Kind() {
+	case reflect.String:
+		s := r.String(v.String())
+		if s != v.String() {
+			nv = reflect.New(v.Type()).Elem()
+			nv.SetString(s)
+			changed = true
+		}
+	case reflect.Interface:
+		if v.IsNil() {
+			return
+		}
+		var ev reflect.Value
+		ev, changed = r.value(v.Elem(), depth+1)
+		if changed {
+			nv = reflect.New(v.Type()).Elem()
+			nv.Set(ev)
+		}
+	case reflect.Ptr:
+		if v.IsNil() || v.Elem().Kind() != reflect.Struct {
+			return
+		}
+		var ev reflect.Value
+		ev, changed = r.value(v.Elem(), depth+1)
+		if changed {
+			nv = reflect.New(v.Type().Elem())
+			nv.Elem().Set(ev)
+		}
+	case reflect.Struct:
+		nv, changed = r.structValue(v, depth)
+	case reflect.Map:
+		nv, changed = r.mapValue(v, depth)
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return
+		}
+		for i := 0; i < v.Len(); i++ {
+			ev, ok := r.value(v.Index(i), depth+1)
+			if !ok {
+				continue
+			}
+			if !changed {
+				changed = true
+				if v.Kind() == reflect.Slice {
+					nv = reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+				} else {
+					nv = reflect.New(v.Type()).Elem()
+				}
+				reflect.Copy(nv, v)
+			}
+			nv.Index(i).Set(ev)
+		}
+	}
+	return
+}
+
+func (r *Redactor) mapValue(v reflect.Value, depth int) (nv reflect.Value, changed bool) {
+	if v.IsNil() || v.Type().Key().Kind() != reflect.String {
+		return
+	}
+	masked := make(map[string]reflect.Value)
+	iter := v.MapRange()
+	for iter.Next() {
+		k := iter.Key()
+		if r.MatchKey(k.String()) {
+			masked[k.String()] = r.mask(v.Type().Elem())
+			continue
+		}
+		if ev, ok := r.value(iter.Value(), depth+1); ok {
+			masked[k.String()] = ev
+		}
+	}
+	if len(masked) == 0 {
+		return
+	}
+	nv = reflect.MakeMapWithSize(v.Type(), v.Len())
+	iter = v.MapRange()
+	for iter.Next() {
+		ev := iter.Value()
+		if m, ok := masked[iter.Key().String()]; ok {
+			ev = m
+		}
+		nv.SetMapIndex(iter.Key(), ev)
+	}
+	changed = true
+	return
+}
+
+func (r *Redactor) structValue(v reflect.Value, depth int) (nv reflect.Value, changed bool) {
+	t := v.Type()
+	for i, rule := range r.structRules(t) {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		var fv reflect.Value
+		var ok bool
+		if rule {
+			fv, ok = r.mask(f.Type), true
+		} else {
+			fv, ok = r.value(v.Field(i), depth+1)
+		}
+		if !ok {
+			continue
+		}
+		if !changed {
+			changed = true
+			nv = reflect.New(t).Elem()
+			nv.Set(v)
+		}
+		nv.Field(i).Set(fv)
+	}
+	return
+}
+
+// structRules cache whether each field of t should be masked
+func (r *Redactor) structRules(t reflect.Type) []bool {
+	if rules, ok := r.types.Load(t); ok {
+		return rules.([]bool)
+	}
+	rules := make([]bool, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		rules[i] = f.Tag.Get("log") == RedactTag || r.MatchKey(f.Name) || (name != "" && r.MatchKey(name))
+	}
+	r.types.Store(t, rules)
+	return rules
+}
+
+// mask value of type t, string kinds are replaced by mask, others by zero value
+func (r *Redactor) mask(t reflect.Type) reflect.Value {
+	v := reflect.New(t).Elem()
+	switch {
+	case t.Kind() == reflect.String:
+		v.SetString(r.ops.mask)
+	case t.Kind() == reflect.Interface && reflect.TypeOf(r.ops.mask).AssignableTo(t):
+		v.Set(reflect.ValueOf(r.ops.mask))
+	}
+	return v
+}
+
+func (o Options) redactString(s string) string {
+	if o.redactor == nil {
+		return s
+	}
+	return o.redactor.String(s)
+}
@@ -0,0 +1,89 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type redactUser struct {
+	Name        string
+	Password    string
+	Phone       string `log:"redact"`
+	AccessToken string `json:"access_token"`
+	Age         int    `log:"redact"`
+	Tags        map[string]string
+}
+
+func TestRedactor_String(t *testing.T) {
+	r := NewRedactor(WithRedactValues(RedactCardNumber, RedactEmail))
+	for s, expect := range map[string]string{
+		`name:"bob" password:"123456"`:                 `name:"bob" password:"***"`,
+		`{Name:bob Password:123456}`:                   `{Name:bob Password:***}`,
+		`{"user":"bob","refresh_token":"abc"}`:         `{"user":"bob","refresh_token":"***"}`,
+		`Authorization: Bearer xyz.abc`:                `Authorization: ***`,
+		`a=1&secret=2&b=3`:                             `a=1&secret=***&b=3`,
+		`card 6222 0212 3456 7890 123, mail a@b.com`:   `card ***, mail ***`,
+		`no sensitive content, tokenize is not a pair`: `no sensitive content, tokenize is not a pair`,
+	} {
+		if got := r.String(s); got != expect {
+			t.Errorf("expect %s, got %s", expect, got)
+		}
+	}
+}
+
+func TestRedactor_Fields(t *testing.T) {
+	r := NewRedactor(WithRedactKeys("phone"))
+	u := &redactUser{
+		Name:        "bob",
+		Password:    "123456",
+		Phone:       "13800000000",
+		AccessToken: "abc",
+		Age:         18,
+		Tags:        map[string]string{"phone": "13800000000", "city": "sz"},
+	}
+	fields := Fields{
+		"user":     u,
+		"users":    []redactUser{*u},
+		"password": 123456,
+		"err":      errors.New("login failed, password=123456"),
+		"other":    1,
+	}
+	ns := r.Fields(fields)
+
+	ru, ok := ns["user"].(*redactUser)
+	if !ok || ru == u {
+		t.Fatalf("expect a copy of user, got %v", ns["user"])
+	}
+	if ru.Name != "bob" || ru.Password != "***" || ru.Phone != "***" || ru.AccessToken != "***" || ru.Age != 0 ||
+		ru.Tags["phone"] != "***" || ru.Tags["city"] != "sz" {
+		t.Errorf("unexpected redacted user %+v", ru)
+	}
+	if u.Password != "123456" || u.Tags["phone"] != "13800000000" {
+		t.Errorf("expect original user not modified, got %+v", u)
+	}
+	if users, _ := ns["users"].([]redactUser); len(users) != 1 || users[0].Password != "***" {
+		t.Errorf("expect users redacted, got %v", ns["users"])
+	}
+	if ns["password"] != "***" || ns["err"] != "login failed, password=***" || ns["other"] != 1 {
+		t.Errorf("unexpected fields %v", ns)
+	}
+}
+
+func TestWithRedactor(t *testing.T) {
+	for _, flag := range []bool{false, true} {
+		var buf bytes.Buffer
+		w := NewWrapper(
+			WithOutput(&buf),
+			WithSlog(flag),
+			WithCaller(false),
+			WithRedactor(NewRedactor(WithRedactValues(RedactPhone))),
+		)
+		w.WithField("token", "abc").Info("login by %s with password=%s", "13800000000", "123456")
+		out := buf.String()
+		if strings.Contains(out, "abc") || strings.Contains(out, "13800000000") || strings.Contains(out, "123456") {
+			t.Errorf("expect sensitive content redacted, got %s", out)
+		}
+	}
+}
@@ -0,0 +1,346 @@
+package log
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	rotateTimeFormat = "2006-01-02T15-04-05.000"
+	compressSuffix   = ".gz"
+)
+
+var _ io.WriteCloser = (*RotateFile)(nil)
+
+// RotateFile a file writer rotated by size or time,
+// backups are named as name-2006-01-02T15-04-05.000.ext(name-2006-01-02T15-04-05.000-1.ext if exists)
+// and optionally gzip compressed
+type RotateFile struct {
+	ops      RotateOptions
+	filename string
+	lock     sync.Mutex
+	file     *os.File
+	size     int64
+	next     time.Time
+	mill     chan struct{}
+	done     chan struct{}
+	stopped  chan struct{}
+	once     sync.Once
+	closed   bool
+}
+
+// NewRotateFile open or create filename(directory is created if missing), append to it
+func NewRotateFile(filename string, options ...func(*RotateOptions)) (rf *RotateFile, err error) {
+	ops := getRotateOptionsOrSetDefault(nil)
+	for _, f := range options {
+		f(ops)
+	}
+	rf = &RotateFile{
+		ops:      *ops,
+		filename: filename,
+		mill:     make(chan struct{}, 1),
+		done:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+	err = rf.open()
+	if err != nil {
+		close(rf.stopped)
+		return
+	}
+	go rf.millLoop()
+	// clean up backups left by last run
+	rf.millRun()
+	return
+}
+
+func (rf *RotateFile) Write(p []byte) (n int, err error) {
+	rf.lock.Lock()
+	defer rf.lock.Unlock()
+	err = rf.reopen()
+	if err != nil {
+		return
+	}
+	if rf.ops.maxSize > 0 && int64(len(p)) > rf.ops.maxSize {
+		err = fmt.Errorf("write length %d exceeds max file size %d", len(p), rf.ops.maxSize)
+		return
+	}
+	now := rf.now()
+	if (rf.ops.maxSize > 0 && rf.size+int64(len(p)) > rf.ops.maxSize && rf.size > 0) ||
+		(!rf.next.IsZero() && !now.Before(rf.next)) {
+		err = rf.rotate(now)
+		if err != nil {
+			if rf.file == nil {
+				return
+			}
+			// still appending to current file, rotate is tried again by next write
+			rf.error(err)
+		}
+	}
+	n, err = rf.file.Write(p)
+	rf.size += int64(n)
+	return
+}
+
+// Rotate close current file and rename it as backup, then open a new one
+func (rf *RotateFile) Rotate() (err error) {
+	rf.lock.Lock()
+	defer rf.lock.Unlock()
+	err = rf.reopen()
+	if err != nil {
+		return
+	}
+	err = rf.rotate(rf.now())
+	return
+}
+
+// Close current file and stop background compress/cleanup, it waits for the running one
+func (rf *RotateFile) Close() (err error) {
+	rf.lock.Lock()
+	rf.closed = true
+	if rf.file != nil {
+		err = rf.file.Close()
+		rf.file = nil
+	}
+	rf.lock.Unlock()
+	rf.once.Do(func() {
+		close(rf.done)
+	})
+	<-rf.stopped
+	return
+}
+
+func (rf *RotateFile) now() time.Time {
+	if rf.ops.localTime {
+		return time.Now()
+	}
+	return time.Now().UTC()
+}
+
+func (rf *RotateFile) open() (err error) {
+	err = os.MkdirAll(filepath.Dir(rf.filename), 0o755)
+	if err != nil {
+		return
+	}
+	f, err := os.OpenFile(rf.filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return
+	}
+	rf.file = f
+	rf.size = info.Size()
+	rf.next = rf.nextRotate(rf.now())
+	return
+}
+
+// nextRotate the next interval boundary, intervals are aligned to zero time, e.g. 24h rotates at midnight
+func (rf *RotateFile) nextRotate(now time.Time) time.Time {
+	if rf.ops.interval <= 0 {
+		return time.Time{}
+	}
+	_, offset := now.Zone()
+	shift := time.Duration(offset) * time.Second
+	return now.Add(shift).Truncate(rf.ops.interval).Add(rf.ops.interval).Add(-shift)
+}
+
+// reopen open filename again if last rotate failed to, a closed file is not reopened
+func (rf *RotateFile) reopen() (err error) {
+	if rf.closed {
+		err = os.ErrClosed
+		return
+	}
+	if rf.file == nil {
+		err = rf.open()
+	}
+	return
+}
+
+// rotate rename current file as backup and open a new one,
+// if rename fails current file is opened again, if open fails rf.file is nil and reopened by next write
+func (rf *RotateFile) rotate(now time.Time) (err error) {
+	err = rf.file.Close()
+	rf.file = nil
+	if err != nil {
+		return
+	}
+	if rf.size > 0 {
+		err = os.Rename(rf.filename, rf.backupName(now))
+		if err != nil {
+			if e := rf.open(); e != nil {
+				rf.error(e)
+			}
+			return
+		}
+	}
+	err = rf.open()
+	if err != nil {
+		return
+	}
+	select {
+	case rf.mill <- struct{}{}:
+	default:
+	}
+	return
+}
+
+// backupName name of t, a sequence is appended if it exists, rename would overwrite it
+func (rf *RotateFile) backupName(t time.Time) (name string) {
+	dir := filepath.Dir(rf.filename)
+	prefix, ext := rf.prefixAndExt()
+	ts := t.Format(rotateTimeFormat)
+	for seq := 0; ; seq++ {
+		if seq > 0 {
+			ts = strings.Join([]string{t.Format(rotateTimeFormat), strconv.Itoa(seq)}, "-")
+		}
+		name = filepath.Join(dir, strings.Join([]string{prefix, ts, ext}, ""))
+		if !exists(name) && !exists(strings.Join([]string{name, compressSuffix}, "")) {
+			return
+		}
+	}
+}
+
+func exists(name string) bool {
+	_, err := os.Lstat(name)
+	return err == nil
+}
+
+func (rf *RotateFile) prefixAndExt() (prefix, ext string) {
+	name := filepath.Base(rf.filename)
+	ext = filepath.Ext(name)
+	prefix = strings.Join([]string{strings.TrimSuffix(name, ext), "-"}, "")
+	return
+}
+
+func (rf *RotateFile) millLoop() {
+	defer close(rf.stopped)
+	for {
+		select {
+		case <-rf.done:
+			return
+		case <-rf.mill:
+			rf.millRun()
+		}
+	}
+}
+
+type backup struct {
+	name string
+	t    time.Time
+	seq  int
+}
+
+// millRun compress backups and remove the ones exceed max backups or max age
+func (rf *RotateFile) millRun() {
+	dir := filepath.Dir(rf.filename)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		rf.error(err)
+		return
+	}
+	// backup names are formatted in the same location
+	loc := time.UTC
+	if rf.ops.localTime {
+		loc = time.Local
+	}
+	prefix, ext := rf.prefixAndExt()
+	backups := make([]backup, 0, len(entries))
+	for _, item := range entries {
+		if item.IsDir() {
+			continue
+		}
+		name := item.Name()
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		ts := strings.TrimPrefix(name, prefix)
+		ts = strings.TrimSuffix(ts, compressSuffix)
+		if !strings.HasSuffix(ts, ext) {
+			continue
+		}
+		ts = strings.TrimSuffix(ts, ext)
+		var seq int
+		if len(ts) > len(rotateTimeFormat) {
+			rest := ts[len(rotateTimeFormat):]
+			v, e := strconv.Atoi(strings.TrimPrefix(rest, "-"))
+			if !strings.HasPrefix(rest, "-") || e != nil {
+				continue
+			}
+			ts, seq = ts[:len(rotateTimeFormat)], v
+		}
+		t, e := time.ParseInLocation(rotateTimeFormat, ts, loc)
+		if e != nil {
+			continue
+		}
+		backups = append(backups, backup{name: filepath.Join(dir, name), t: t, seq: seq})
+	}
+	// newest first
+	sort.Slice(backups, func(i, j int) bool {
+		if backups[i].t.Equal(backups[j].t) {
+			return backups[i].seq > backups[j].seq
+		}
+		return backups[i].t.After(backups[j].t)
+	})
+	var cutoff time.Time
+	if rf.ops.maxAge > 0 {
+		cutoff = time.Now().Add(-rf.ops.maxAge)
+	}
+	for i, item := range backups {
+		if (rf.ops.maxBackups > 0 && i >= rf.ops.maxBackups) || (!cutoff.IsZero() && item.t.Before(cutoff)) {
+			e := os.Remove(item.name)
+			if e != nil {
+				rf.error(fmt.Errorf("remove log file %s failed: %w", item.name, e))
+			}
+			continue
+		}
+		if rf.ops.compress && !strings.HasSuffix(item.name, compressSuffix) {
+			e := compressFile(item.name)
+			if e != nil {
+				rf.error(fmt.Errorf("compress log file %s failed: %w", item.name, e))
+			}
+		}
+	}
+}
+
+func (rf *RotateFile) error(err error) {
+	if rf.ops.onError != nil {
+		rf.ops.onError(err)
+	}
+}
+
+func compressFile(name string) (err error) {
+	src, err := os.Open(name)
+	if err != nil {
+		return
+	}
+	defer src.Close()
+	target := strings.Join([]string{name, compressSuffix}, "")
+	dst, err := os.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o644)
+	if err != nil {
+		return
+	}
+	gz := gzip.NewWriter(dst)
+	_, err = io.Copy(gz, src)
+	if err == nil {
+		err = gz.Close()
+	}
+	if e := dst.Close(); err == nil {
+		err = e
+	}
+	if err != nil {
+		_ = os.Remove(target)
+		return
+	}
+	err = os.Remove(name)
+	return
+}
@@ -0,0 +1,140 @@
+package log
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// SampleDroppedKey total dropped lines in summary line, dropped of each level is in SampleDroppedKey.level
+const SampleDroppedKey = "dropped"
+
+type sampleKey struct {
+	level    Level
+	template string
+}
+
+type tokenBucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func (b *tokenBucket) take(now time.Time) bool {
+	if !b.last.IsZero() {
+		b.tokens += now.Sub(b.last).Seconds() * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+	}
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// sampler drop lines by template counter and level token bucket,
+// a summary line of dropped counts is written after each interval which has dropped lines
+type sampler struct {
+	first      int
+	thereafter int
+	interval   time.Duration
+	lock       sync.Mutex
+	start      time.Time
+	counts     map[sampleKey]int
+	buckets    map[Level]*tokenBucket
+	dropped    map[Level]uint64
+	timer      *time.Timer
+	emit       func(level Level, msg string, fields Fields)
+}
+
+func newSampler(ops *Options) *sampler {
+	if ops.sampleFirst <= 0 && len(ops.rateLimits) == 0 {
+		return nil
+	}
+	s := &sampler{
+		first:      ops.sampleFirst,
+		thereafter: ops.sampleThereafter,
+		interval:   ops.sampleInterval,
+		counts:     make(map[sampleKey]int),
+		buckets:    make(map[Level]*tokenBucket),
+		dropped:    make(map[Level]uint64),
+	}
+	if s.interval <= 0 {
+		s.interval = time.Second
+	}
+	for level, item := range ops.rateLimits {
+		s.buckets[level] = &tokenBucket{
+			rate:   float64(item[0]),
+			burst:  float64(item[1]),
+			tokens: float64(item[1]),
+		}
+	}
+	return s
+}
+
+// allow the first N lines of each template in interval, then every Mth, and the level token bucket has token.
+// fatal and panic are never dropped
+func (s *sampler) allow(level Level, template string) bool {
+	if level <= FatalLevel {
+		return true
+	}
+	now := time.Now()
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if now.Sub(s.start) >= s.interval {
+		s.start = now
+		s.counts = make(map[sampleKey]int)
+	}
+	if s.first > 0 {
+		key := sampleKey{level: level, template: template}
+		n := s.counts[key] + 1
+		s.counts[key] = n
+		if n > s.first && (s.thereafter <= 0 || (n-s.first)%s.thereafter != 0) {
+			s.drop(level)
+			return false
+		}
+	}
+	if b, ok := s.buckets[level]; ok && !b.take(now) {
+		s.drop(level)
+		return false
+	}
+	return true
+}
+
+func (s *sampler) drop(level Level) {
+	s.dropped[level]++
+	if s.timer == nil {
+		s.timer = time.AfterFunc(s.interval, s.summary)
+	}
+}
+
+func (s *sampler) summary() {
+	s.lock.Lock()
+	dropped := s.dropped
+	s.dropped = make(map[Level]uint64)
+	s.timer = nil
+	emit := s.emit
+	s.lock.Unlock()
+	if emit == nil || len(dropped) == 0 {
+		return
+	}
+	fields := make(Fields, len(dropped)+1)
+	var total uint64
+	for level, n := range dropped {
+		fields[strings.Join([]string{SampleDroppedKey, level.String()}, ".")] = n
+		total += n
+	}
+	fields[SampleDroppedKey] = total
+	emit(WarnLevel, "log sampling dropped lines", fields)
+}
+
+func (o Options) sampled(level Level, template string) bool {
+	if o.sampler == nil {
+		return true
+	}
+	return o.sampler.allow(level, template)
+}
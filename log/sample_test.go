@@ -0,0 +1,85 @@
+package log
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type syncBuffer struct {
+	lock sync.Mutex
+	buf  bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.buf.String()
+}
+
+func TestWithSampling(t *testing.T) {
+	var buf syncBuffer
+	w := NewWrapper(
+		WithOutput(&buf),
+		WithCaller(false),
+		WithSampling(2, 5),
+		WithSamplingInterval(100),
+	)
+	for i := 0; i < 12; i++ {
+		w.Warn("test warn %d", i)
+		w.Info("test info")
+	}
+	// 0, 1, 6, 11
+	if n := strings.Count(buf.String(), "test warn"); n != 4 {
+		t.Errorf("expect 4 warn lines, got %d", n)
+	}
+	if n := strings.Count(buf.String(), "test info"); n != 4 {
+		t.Errorf("expect 4 info lines, got %d", n)
+	}
+	time.Sleep(200 * time.Millisecond)
+	out := buf.String()
+	if !strings.Contains(out, "log sampling dropped lines") || !strings.Contains(out, "dropped=16") || !strings.Contains(out, "dropped.warn=8") {
+		t.Errorf("expect summary line, got %s", out)
+	}
+	// counter is reset
+	w.Warn("test warn %d", 12)
+	if n := strings.Count(buf.String(), "test warn"); n != 5 {
+		t.Errorf("expect 5 warn lines, got %d", n)
+	}
+}
+
+func TestWithRateLimit(t *testing.T) {
+	var buf syncBuffer
+	w := NewWrapper(
+		WithSlog(true),
+		WithOutput(&buf),
+		WithCaller(false),
+		WithRateLimit(ErrorLevel, 10, 3),
+		WithSamplingInterval(100),
+	)
+	for i := 0; i < 10; i++ {
+		w.Error("test error %d", i)
+		w.Info("test info %d", i)
+	}
+	out := buf.String()
+	if n := strings.Count(out, "test error"); n != 3 {
+		t.Errorf("expect burst 3 error lines, got %d", n)
+	}
+	if n := strings.Count(out, "test info"); n != 10 {
+		t.Errorf("expect info not limited, got %d", n)
+	}
+	time.Sleep(200 * time.Millisecond)
+	w.Error("test error after refill")
+	out = buf.String()
+	if !strings.Contains(out, "dropped.error=7") || !strings.Contains(out, "test error after refill") {
+		t.Errorf("expect summary and refilled token, got %s", out)
+	}
+}
@@ -0,0 +1,320 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	slogLevelTrace = slog.Level(-8)
+	slogLevelFatal = slog.Level(12)
+	slogLevelPanic = slog.Level(16)
+)
+
+var _ Logger = (*slogLog)(nil)
+
+type slogLog struct {
+	entry
+	handler slog.Handler
+}
+
+func newSlogLog(ops *Options) *slogLog {
+	h := ops.slogHandler
+	if h == nil {
+		// level is checked by enabled, it may be changed at runtime
+		h = newSlogTextOrJSONHandler(ops, ops.output, TraceLevel)
+	}
+	if len(ops.levelOutputs) > 0 {
+		hs := []slog.Handler{h}
+		for _, item := range ops.levelOutputs {
+			hs = append(hs, newSlogTextOrJSONHandler(ops, item.w, item.level))
+		}
+		h = &fanoutHandler{hs: hs}
+	}
+	l := &slogLog{
+		entry: entry{
+			ops: ops,
+			ctx: context.Background(),
+		},
+		handler: h,
+	}
+	if ops.sampler != nil {
+		ops.sampler.emit = func(level Level, msg string, fields Fields) {
+			l.with(l.withFields(fields)).output(level, msg)
+		}
+	}
+	ops.logger = overrideKratos(l)
+	return l
+}
+
+// newSlogTextOrJSONHandler built-in handler, keep the same time format and level name as logrus
+func newSlogTextOrJSONHandler(ops *Options, w io.Writer, level Level) slog.Handler {
+	format := ops.textFormatter.TimestampFormat
+	if ops.json {
+		format = ops.jsonFormatter.TimestampFormat
+	}
+	hOps := &slog.HandlerOptions{
+		Level: logLevelToSlogLevel(level),
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) > 0 {
+				return a
+			}
+			switch a.Key {
+			case slog.TimeKey:
+				if t, ok := a.Value.Any().(time.Time); ok && format != "" {
+					a.Value = slog.StringValue(t.Format(format))
+				}
+			case slog.LevelKey:
+				if lvl, ok := a.Value.Any().(slog.Level); ok {
+					// logrus prints warning rather than warn
+					a.Value = slog.StringValue(logrus.Level(slogLevelToLogLevel(lvl)).String())
+				}
+			}
+			return a
+		},
+	}
+	if ops.json {
+		return slog.NewJSONHandler(w, hOps)
+	}
+	return slog.NewTextHandler(w, hOps)
+}
+
+func (l *slogLog) with(e entry) *slogLog {
+	return &slogLog{
+		entry:   e,
+		handler: l.handler,
+	}
+}
+
+func (l *slogLog) Options() Options {
+	return *l.ops
+}
+
+func (l *slogLog) WithFields(fields Fields) Logger {
+	return l.with(l.withFields(fields))
+}
+
+func (l *slogLog) Named(name string) Logger {
+	return l.with(l.named(name))
+}
+
+func (l *slogLog) WithContext(ctx context.Context) Logger {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return l.with(l.withContext(ctx))
+}
+
+func (l *slogLog) Log(level Level, args ...interface{}) {
+	if !l.enabled(level) {
+		return
+	}
+	msg := fmt.Sprint(args...)
+	if !l.ops.sampled(level, msg) {
+		return
+	}
+	l.output(level, msg)
+}
+
+func (l *slogLog) Logf(level Level, format string, args ...interface{}) {
+	if !l.enabled(level) || !l.ops.sampled(level, format) {
+		return
+	}
+	l.output(level, fmt.Sprintf(format, args...))
+}
+
+// enabled level of logger and handler
+func (l *slogLog) enabled(level Level) bool {
+	return l.entry.enabled(level) && l.handler.Enabled(l.ctx, logLevelToSlogLevel(level))
+}
+
+func (l *slogLog) output(level Level, msg string) {
+	ns := l.values(level)
+	keys := make([]string, 0, len(ns))
+	for k := range ns {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	msg = l.ops.redactString(msg)
+	r := slog.NewRecord(time.Now(), logLevelToSlogLevel(level), msg, 0)
+	for _, k := range keys {
+		v := ns[k]
+		if err, ok := v.(error); ok {
+			// keep the same output as logrus
+			v = err.Error()
+		}
+		r.AddAttrs(slog.Any(k, v))
+	}
+	_ = l.handler.Handle(l.ctx, r)
+	l.ops.fire(l.ctx, level, msg, ns)
+	putFields(ns)
+}
+
+var _ slog.Handler = (*slogHandler)(nil)
+
+// slogHandler expose Logger as slog.Handler
+type slogHandler struct {
+	log    Logger
+	prefix string
+	attrs  Fields
+}
+
+// NewSlogHandler expose l as slog.Handler,
+// so libraries using slog have the same format, caller and valuers as l.
+// groups are flattened into dotted keys
+func NewSlogHandler(l Logger) slog.Handler {
+	return &slogHandler{
+		log:   l,
+		attrs: make(Fields),
+	}
+}
+
+func (h *slogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return h.log.Options().Level().Enabled(slogLevelToLogLevel(level))
+}
+
+func (h *slogHandler) Handle(ctx context.Context, r slog.Record) error {
+	fields := copyFields(h.attrs)
+	r.Attrs(func(a slog.Attr) bool {
+		addSlogAttr(fields, h.prefix, a)
+		return true
+	})
+	l := h.log
+	if ctx != nil {
+		l = l.WithContext(ctx)
+	}
+	if len(fields) > 0 {
+		l = l.WithFields(fields)
+	}
+	l.Log(slogLevelToLogLevel(r.Level), r.Message)
+	return nil
+}
+
+func (h *slogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	fields := copyFields(h.attrs)
+	for _, a := range attrs {
+		addSlogAttr(fields, h.prefix, a)
+	}
+	return &slogHandler{
+		log:    h.log,
+		prefix: h.prefix,
+		attrs:  fields,
+	}
+}
+
+func (h *slogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &slogHandler{
+		log:    h.log,
+		prefix: strings.Join([]string{h.prefix, name, "."}, ""),
+		attrs:  h.attrs,
+	}
+}
+
+func addSlogAttr(fields Fields, prefix string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		p := prefix
+		if a.Key != "" {
+			p = strings.Join([]string{prefix, a.Key, "."}, "")
+		}
+		for _, item := range a.Value.Group() {
+			addSlogAttr(fields, p, item)
+		}
+		return
+	}
+	fields[strings.Join([]string{prefix, a.Key}, "")] = a.Value.Any()
+}
+
+// fanoutHandler send record to each enabled handler
+type fanoutHandler struct {
+	hs []slog.Handler
+}
+
+func (h *fanoutHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, item := range h.hs {
+		if item.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *fanoutHandler) Handle(ctx context.Context, r slog.Record) (err error) {
+	for _, item := range h.hs {
+		if !item.Enabled(ctx, r.Level) {
+			continue
+		}
+		e := item.Handle(ctx, r.Clone())
+		if e != nil && err == nil {
+			err = e
+		}
+	}
+	return
+}
+
+func (h *fanoutHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	hs := make([]slog.Handler, len(h.hs))
+	for i, item := range h.hs {
+		hs[i] = item.WithAttrs(attrs)
+	}
+	return &fanoutHandler{hs: hs}
+}
+
+func (h *fanoutHandler) WithGroup(name string) slog.Handler {
+	hs := make([]slog.Handler, len(h.hs))
+	for i, item := range h.hs {
+		hs[i] = item.WithGroup(name)
+	}
+	return &fanoutHandler{hs: hs}
+}
+
+func logLevelToSlogLevel(level Level) slog.Level {
+	switch level {
+	case PanicLevel:
+		return slogLevelPanic
+	case FatalLevel:
+		return slogLevelFatal
+	case ErrorLevel:
+		return slog.LevelError
+	case WarnLevel:
+		return slog.LevelWarn
+	case DebugLevel:
+		return slog.LevelDebug
+	case TraceLevel:
+		return slogLevelTrace
+	default:
+		return slog.LevelInfo
+	}
+}
+
+func slogLevelToLogLevel(level slog.Level) Level {
+	switch {
+	case level >= slogLevelPanic:
+		return PanicLevel
+	case level >= slogLevelFatal:
+		return FatalLevel
+	case level >= slog.LevelError:
+		return ErrorLevel
+	case level >= slog.LevelWarn:
+		return WarnLevel
+	case level >= slog.LevelInfo:
+		return InfoLevel
+	case level >= slog.LevelDebug:
+		return DebugLevel
+	default:
+		return TraceLevel
+	}
+}
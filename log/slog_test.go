@@ -0,0 +1,83 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestSlog(t *testing.T) {
+	var buf, errs bytes.Buffer
+	w := NewWrapper(
+		WithSlog(true),
+		WithOutput(&buf),
+		WithLevelOutput(ErrorLevel, &errs),
+		WithValuer("service", "test"),
+		WithValuer("empty", ""),
+	)
+	w.Debug("not print since info level")
+	w.WithField("field1", 1).Info("test info with %s", "format")
+	w.WithError(errors.New("something error")).Error("test error")
+	w.Warn("test warn")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expect 3 lines, got %q", buf.String())
+	}
+	if !strings.Contains(lines[2], "level=warning") {
+		t.Errorf("expect the same level name as logrus, got %s", lines[2])
+	}
+	for _, item := range []string{"level=info", `msg="test info with format"`, "field1=1", "service=test", "caller="} {
+		if !strings.Contains(lines[0], item) {
+			t.Errorf("expect %s in %s", item, lines[0])
+		}
+	}
+	if strings.Contains(lines[0], "empty=") {
+		t.Errorf("expect empty value skipped, got %s", lines[0])
+	}
+	if !strings.Contains(lines[1], `err="something error"`) || !strings.Contains(errs.String(), "test error") {
+		t.Errorf("expect error line in both outputs, got %s %s", lines[1], errs.String())
+	}
+}
+
+func TestWithSlogHandler(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(
+		WithSlogHandler(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})),
+		WithLevel(DebugLevel),
+		WithCaller(false),
+	)
+	l.WithFields(Fields{"k": "v"}).Log(DebugLevel, "test debug")
+	m := make(map[string]interface{})
+	err := json.Unmarshal(buf.Bytes(), &m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m["msg"] != "test debug" || m["k"] != "v" || m["level"] != "DEBUG" {
+		t.Errorf("unexpected output %v", m)
+	}
+}
+
+func TestNewSlogHandler(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWrapper(WithOutput(&buf), WithJSON(true))
+	sl := w.Slog().With("a", 1).WithGroup("g")
+	sl.Debug("not print since info level")
+	sl.InfoContext(context.Background(), "test info", "b", 2, slog.Group("sub", "c", 3))
+
+	m := make(map[string]interface{})
+	err := json.Unmarshal(buf.Bytes(), &m)
+	if err != nil {
+		t.Fatalf("expect one json line, got %q", buf.String())
+	}
+	if m["msg"] != "test info" || m["level"] != "info" || m["a"] != float64(1) || m["g.b"] != float64(2) || m["g.sub.c"] != float64(3) {
+		t.Errorf("unexpected output %v", m)
+	}
+	if c, _ := m[CallerKey].(string); !strings.Contains(c, "slog_test.go:") {
+		t.Errorf("expect slog frames skipped by caller, got %v", m[CallerKey])
+	}
+}
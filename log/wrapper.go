@@ -2,6 +2,7 @@ package log
 
 import (
 	"context"
+	"log/slog"
 	"os"
 )
 
@@ -9,6 +10,11 @@ type Wrapper struct {
 	log Logger
 }
 
+// leveler implemented by built-in loggers, level is checked by pointer of options since Options() copies them
+type leveler interface {
+	enabled(level Level) bool
+}
+
 func NewWrapper(options ...func(*Options)) *Wrapper {
 	return &Wrapper{
 		log: New(options...),
@@ -37,9 +43,33 @@ func (w *Wrapper) Error(args ...interface{}) {
 
 func (w *Wrapper) Fatal(args ...interface{}) {
 	w.print(FatalLevel, args...)
+	_ = w.Flush()
 	os.Exit(1)
 }
 
+// Flush outputs and hooks which have Flush() error, such as AsyncWriter
+func (w *Wrapper) Flush() (err error) {
+	ops := w.log.Options()
+	if ops.output != nil {
+		err = flushWriter(ops.output)
+	}
+	for _, item := range ops.levelOutputs {
+		if e := flushWriter(item.w); e != nil && err == nil {
+			err = e
+		}
+	}
+	for _, item := range ops.hooks {
+		f, ok := item.(flusher)
+		if !ok {
+			continue
+		}
+		if e := f.Flush(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return
+}
+
 func (w *Wrapper) WithError(err error) *Wrapper {
 	l := w.log
 	if err != nil {
@@ -72,8 +102,20 @@ func (w *Wrapper) Options() Options {
 	return w.log.Options()
 }
 
+// Slog a slog.Logger writes to the same logger
+func (w *Wrapper) Slog() *slog.Logger {
+	return slog.New(NewSlogHandler(w.log))
+}
+
+func (w *Wrapper) enabled(level Level) bool {
+	if l, ok := w.log.(leveler); ok {
+		return l.enabled(level)
+	}
+	return w.log.Options().Level().Enabled(level)
+}
+
 func (w *Wrapper) print(level Level, args ...interface{}) {
-	if !w.log.Options().level.Enabled(level) {
+	if !w.enabled(level) {
 		return
 	}
 	if len(args) > 1 {
@@ -4,19 +4,39 @@ go 1.23
 
 toolchain go1.23.4
 
-replace github.com/go-cinch/common/log => ../../log
+replace (
+	github.com/go-cinch/common/constant => ../../constant
+	github.com/go-cinch/common/i18n => ../../i18n
+	github.com/go-cinch/common/log => ../../log
+	github.com/go-cinch/common/middleware/i18n => ../i18n
+)
 
 require (
+	github.com/go-cinch/common/constant v1.0.0
 	github.com/go-cinch/common/log v1.2.0
+	github.com/go-cinch/common/middleware/i18n v1.0.0
 	github.com/go-kratos/kratos/v2 v2.8.3
+	go.opentelemetry.io/otel v1.34.0
+	go.opentelemetry.io/otel/metric v1.34.0
+	go.opentelemetry.io/otel/sdk/metric v1.34.0
 )
 
 require (
-	github.com/go-playground/form/v4 v4.2.0 // indirect
+	github.com/BurntSushi/toml v1.3.2 // indirect
+	github.com/go-cinch/common/i18n v1.0.7 // indirect
+	github.com/go-logr/logr v1.4.2 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+	github.com/go-playground/form/v4 v4.2.1 // indirect
 	github.com/golang/protobuf v1.5.4 // indirect
-	github.com/kr/text v0.2.0 // indirect
+	github.com/google/uuid v1.6.0 // indirect
+	github.com/nicksnyder/go-i18n/v2 v2.2.1 // indirect
+	github.com/pkg/errors v0.9.1 // indirect
 	github.com/sirupsen/logrus v1.9.3 // indirect
-	golang.org/x/sys v0.18.0 // indirect
+	go.opentelemetry.io/auto/sdk v1.1.0 // indirect
+	go.opentelemetry.io/otel/sdk v1.34.0 // indirect
+	go.opentelemetry.io/otel/trace v1.34.0 // indirect
+	golang.org/x/sys v0.29.0 // indirect
+	golang.org/x/text v0.14.0 // indirect
 	google.golang.org/genproto/googleapis/rpc v0.0.0-20240102182953-50ed04b92917 // indirect
 	google.golang.org/grpc v1.61.1 // indirect
 	google.golang.org/protobuf v1.34.1 // indirect
@@ -2,9 +2,10 @@ package logging
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"reflect"
-	"runtime"
+	"strings"
 	"time"
 
 	"github.com/go-cinch/common/log"
@@ -14,97 +15,249 @@ import (
 )
 
 // Server is an server logging middleware.
-func Server() middleware.Middleware {
+func Server(options ...func(*Options)) middleware.Middleware {
+	ops := getOptionsOrSetDefault(nil)
+	for _, f := range options {
+		f(ops)
+	}
+	r := newRecovery()
 	return func(handler middleware.Handler) middleware.Handler {
 		return func(ctx context.Context, req interface{}) (reply interface{}, err error) {
-			var (
-				code      int32
-				reason    string
-				kind      string
-				operation string
-			)
-			startTime := time.Now()
-			if info, ok := transport.FromServerContext(ctx); ok {
-				kind = info.Kind().String()
+			info, _ := transport.FromServerContext(ctx)
+			if ops.skip(info) {
+				// skipped operations are not logged but still recovered
+				defer func() {
+					if e := recover(); e != nil {
+						err = r.recover(ctx, req, e)
+					}
+				}()
+				return handler(ctx, req)
+			}
+			var operation string
+			if info != nil {
 				operation = info.Operation()
 			}
-			var stack string
+			startTime := time.Now()
 			l := log.
 				WithContext(ctx).
-				WithFields(log.Fields{
-					"kind": kind,
-					"args": extractArgs(req),
-				})
+				WithFields(ops.fields(info, req))
 			defer func() {
-				e := recover()
-				if e != nil {
-					buf := make([]byte, 64<<10) //nolint:gomnd
-					n := runtime.Stack(buf, false)
-					buf = buf[:n]
-					stack = fmt.Sprintf("%s", buf)
-				}
-				if stack != "" {
-					l.WithFields(log.Fields{
-						"stack": stack,
-					}).Warn(operation)
-				}
-				if e != nil {
-					err = errors.InternalServer("UNKNOWN", "unknown request error")
+				// fallback if Recovery is not used after Server
+				if e := recover(); e != nil {
+					err = r.recover(ctx, req, e)
+					ops.output(l, operation, nil, err, time.Since(startTime))
 				}
 			}()
 			reply, err = handler(ctx, req)
-			if se := errors.FromError(err); se != nil {
-				code = se.Code
-				reason = se.Reason
+			ops.output(l, operation, reply, err, time.Since(startTime))
+			return
+		}
+	}
+}
+
+// Client is an client logging middleware, outbound calls are logged with the same format as Server.
+func Client(options ...func(*Options)) middleware.Middleware {
+	ops := getOptionsOrSetDefault(nil)
+	for _, f := range options {
+		f(ops)
+	}
+	return func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (reply interface{}, err error) {
+			info, _ := transport.FromClientContext(ctx)
+			if ops.skip(info) {
+				return handler(ctx, req)
 			}
-			stack = extractError(err)
-			l = l.WithFields(log.Fields{
-				"resp":    extractReply(reply),
-				"code":    code,
-				"reason":  reason,
-				"latency": time.Since(startTime).Seconds(),
-			})
-			if stack != "" {
-				return
+			var operation string
+			fields := ops.fields(info, req)
+			if info != nil {
+				operation = info.Operation()
+				fields["endpoint"] = info.Endpoint()
 			}
-			l.Info(operation)
+			startTime := time.Now()
+			l := log.
+				WithContext(ctx).
+				WithFields(fields)
+			reply, err = handler(ctx, req)
+			ops.output(l, operation, reply, err, time.Since(startTime))
 			return
 		}
 	}
 }
 
-// extractArgs returns the string of the req
-func extractArgs(req interface{}) string {
-	if stringer, ok := req.(fmt.Stringer); ok {
-		return truncate(stringer.String())
+func (ops Options) skip(info transport.Transporter) bool {
+	if info == nil {
+		return false
+	}
+	_, ok := ops.skips[info.Operation()]
+	return ok
+}
+
+// fields kind, args and headers
+func (ops Options) fields(info transport.Transporter, req interface{}) log.Fields {
+	fields := log.Fields{}
+	if info != nil {
+		fields["kind"] = info.Kind().String()
+		for _, k := range ops.headers {
+			v := info.RequestHeader().Get(k)
+			if v == "" {
+				continue
+			}
+			if ops.redactor != nil && ops.redactor.MatchKey(k) {
+				v = fmt.Sprint(ops.redactor.Fields(log.Fields{k: v})[k])
+			}
+			fields[strings.Join([]string{"header", strings.ToLower(k)}, ".")] = v
+		}
+	}
+	if ops.args {
+		fields["args"] = ops.extract(req, ops.argsKeys)
 	}
-	return truncate(fmt.Sprintf("%+v", req))
+	return fields
 }
 
-// extractReply returns the string of the reply
-func extractReply(reply interface{}) string {
-	if interfaceIsNil(reply) {
+// output log reply and result at level of outcome
+func (ops Options) output(l *log.Wrapper, operation string, reply interface{}, err error, latency time.Duration) {
+	var (
+		code   int32
+		reason string
+	)
+	level := ops.successLevel
+	if se := errors.FromError(err); se != nil {
+		code = se.Code
+		reason = se.Reason
+		level = ops.serverErrorLevel
+		if code >= 400 && code < 500 {
+			level = ops.clientErrorLevel
+		}
+	}
+	fields := log.Fields{
+		"code":    code,
+		"reason":  reason,
+		"latency": latency.Seconds(),
+	}
+	if ops.reply {
+		fields["resp"] = ops.extract(reply, ops.replyKeys)
+	}
+	if ops.slow > 0 && latency >= time.Duration(ops.slow)*time.Millisecond {
+		fields["slow"] = true
+		// lower level is more severe
+		if ops.slowLevel < level {
+			level = ops.slowLevel
+		}
+	}
+	l = l.WithFields(fields).WithError(err)
+	switch level {
+	case log.TraceLevel:
+		l.Trace(operation)
+	case log.DebugLevel:
+		l.Debug(operation)
+	case log.InfoLevel:
+		l.Info(operation)
+	case log.WarnLevel:
+		l.Warn(operation)
+	default:
+		// never exit in middleware
+		l.Error(operation)
+	}
+}
+
+// extract returns the string of the req or reply
+func (ops Options) extract(v interface{}, keys []string) string {
+	if interfaceIsNil(v) {
 		return ""
 	}
-	if stringer, ok := reply.(fmt.Stringer); ok {
-		return truncate(stringer.String())
+	if ops.redactor != nil {
+		v = ops.redactor.Value(v)
+	}
+	if len(keys) > 0 {
+		v = selectKeys(v, keys)
 	}
-	return truncate(fmt.Sprintf("%+v", reply))
+	var s string
+	if stringer, ok := v.(fmt.Stringer); ok {
+		s = stringer.String()
+	} else {
+		s = fmt.Sprintf("%+v", v)
+	}
+	if ops.redactor != nil {
+		s = ops.redactor.String(s)
+	}
+	return ops.truncate(s)
 }
 
-// extractError returns the string of the error
-func extractError(err error) string {
+// selectKeys json string of fields in keys, v is returned if it's not a struct or map
+func selectKeys(v interface{}, keys []string) interface{} {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	m := make(map[string]interface{}, len(keys))
+	switch rv.Kind() {
+	case reflect.Struct:
+		t := rv.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			for _, name := range fieldNames(f) {
+				if containsString(keys, name) {
+					m[name] = rv.Field(i).Interface()
+					break
+				}
+			}
+		}
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return v
+		}
+		for _, k := range keys {
+			if item := rv.MapIndex(reflect.ValueOf(k).Convert(rv.Type().Key())); item.IsValid() {
+				m[k] = item.Interface()
+			}
+		}
+	default:
+		return v
+	}
+	bs, err := json.Marshal(m)
 	if err != nil {
-		return truncate(fmt.Sprintf("%+v", err))
+		return m
+	}
+	return string(bs)
+}
+
+// fieldNames go, json and proto name of struct field
+func fieldNames(f reflect.StructField) (names []string) {
+	names = append(names, f.Name)
+	if name := strings.Split(f.Tag.Get("json"), ",")[0]; name != "" && name != "-" {
+		names = append(names, name)
+	}
+	for _, item := range strings.Split(f.Tag.Get("protobuf"), ",") {
+		if name, ok := strings.CutPrefix(item, "name="); ok {
+			names = append(names, name)
+		}
+		if name, ok := strings.CutPrefix(item, "json="); ok {
+			names = append(names, name)
+		}
 	}
-	return ""
+	return
 }
 
-func truncate(s string) string {
-	if len(s) <= 500 {
+func (ops Options) truncate(s string) string {
+	if ops.maxLength <= 0 || len(s) <= ops.maxLength {
 		return s
 	}
-	return s[:249] + "..." + s[len(s)-248:]
+	if ops.maxLength <= 3 {
+		return s[:ops.maxLength]
+	}
+	// keep head and tail
+	head := (ops.maxLength - 3) / 2
+	tail := ops.maxLength - 3 - head
+	return s[:head] + "..." + s[len(s)-tail:]
+}
+
+func containsString(s []string, v string) bool {
+	for _, item := range s {
+		if item == v {
+			return true
+		}
+	}
+	return false
 }
 
 func interfaceIsNil(i interface{}) bool {
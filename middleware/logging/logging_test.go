@@ -0,0 +1,139 @@
+package logging
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-cinch/common/log"
+	"github.com/go-cinch/common/log/logtest"
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/transport"
+)
+
+type headerCarrier http.Header
+
+func (hc headerCarrier) Get(key string) string { return http.Header(hc).Get(key) }
+
+func (hc headerCarrier) Set(key string, value string) { http.Header(hc).Set(key, value) }
+
+func (hc headerCarrier) Add(key string, value string) { http.Header(hc).Add(key, value) }
+
+func (hc headerCarrier) Keys() []string {
+	keys := make([]string, 0, len(hc))
+	for k := range http.Header(hc) {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+func (hc headerCarrier) Values(key string) []string { return http.Header(hc).Values(key) }
+
+type testTransport struct {
+	operation string
+	header    headerCarrier
+}
+
+func (tr *testTransport) Kind() transport.Kind { return transport.KindHTTP }
+
+func (tr *testTransport) Endpoint() string { return "127.0.0.1:8080" }
+
+func (tr *testTransport) Operation() string { return tr.operation }
+
+func (tr *testTransport) RequestHeader() transport.Header { return tr.header }
+
+func (tr *testTransport) ReplyHeader() transport.Header { return headerCarrier{} }
+
+type createReq struct {
+	Name     string `json:"name"`
+	Password string `json:"password"`
+	Remark   string `json:"remark"`
+}
+
+func newServerContext(operation string) context.Context {
+	header := headerCarrier{}
+	header.Set("Authorization", "Bearer xxx")
+	header.Set("X-Request-Id", "abc")
+	return transport.NewServerContext(context.Background(), &testTransport{operation: operation, header: header})
+}
+
+func TestServer(t *testing.T) {
+	logs := logtest.Swap(t)
+	m := Server(
+		WithArgsKeys("name", "password"),
+		WithRedactor(log.NewRedactor()),
+		WithHeader("Authorization", "X-Request-Id"),
+		WithSkip("/health"),
+	)
+	h := m(func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "ok", nil
+	})
+	_, _ = h(newServerContext("/user/create"), &createReq{Name: "bob", Password: "123456", Remark: "remark"})
+	_, _ = h(newServerContext("/health"), nil)
+
+	entries := logs.All()
+	if len(entries) != 1 {
+		t.Fatalf("expect 1 line, got %+v", entries)
+	}
+	e := entries[0]
+	if e.Level != log.InfoLevel || e.Message != "/user/create" || e.Fields["resp"] != "ok" || e.Fields["header.x-request-id"] != "abc" {
+		t.Errorf("unexpected entry %+v", e)
+	}
+	args, _ := e.Fields["args"].(string)
+	if args != `{"name":"bob","password":"***"}` {
+		t.Errorf("expect selected and redacted args, got %s", args)
+	}
+	if e.Fields["header.authorization"] != "***" {
+		t.Errorf("expect redacted header, got %v", e.Fields["header.authorization"])
+	}
+}
+
+func TestServer_level(t *testing.T) {
+	logs := logtest.Swap(t)
+	m := Server(WithSlow(10, log.WarnLevel), WithMaxLength(10))
+	errs := []error{
+		errors.BadRequest("INVALID", "invalid"),
+		errors.InternalServer("INTERNAL", "internal"),
+	}
+	for _, err := range errs {
+		_, _ = m(func(context.Context, interface{}) (interface{}, error) {
+			return nil, err
+		})(newServerContext("/user/create"), strings.Repeat("a", 20))
+	}
+	_, _ = m(func(context.Context, interface{}) (interface{}, error) {
+		time.Sleep(20 * time.Millisecond)
+		return "ok", nil
+	})(newServerContext("/user/create"), nil)
+
+	entries := logs.All()
+	if len(entries) != 3 {
+		t.Fatalf("expect 3 lines, got %+v", entries)
+	}
+	if entries[0].Level != log.WarnLevel || entries[0].Fields["reason"] != "INVALID" || entries[0].Fields["args"] != "aaa...aaaa" {
+		t.Errorf("expect 4xx at warn, got %+v", entries[0])
+	}
+	if entries[1].Level != log.ErrorLevel || entries[1].Fields["code"] != int64(500) {
+		t.Errorf("expect 5xx at error, got %+v", entries[1])
+	}
+	if entries[2].Level != log.WarnLevel || entries[2].Fields["slow"] != true {
+		t.Errorf("expect slow at warn, got %+v", entries[2])
+	}
+}
+
+func TestClient(t *testing.T) {
+	logs := logtest.Swap(t)
+	ctx := transport.NewClientContext(context.Background(), &testTransport{operation: "/user/get", header: headerCarrier{}})
+	_, _ = Client(WithReply(false))(func(context.Context, interface{}) (interface{}, error) {
+		return "ok", nil
+	})(ctx, "req")
+
+	e := logs.FilterMessage("/user/get").All()
+	if len(e) != 1 || e[0].Fields["endpoint"] != "127.0.0.1:8080" || e[0].Fields["args"] != "req" {
+		t.Fatalf("expect client line, got %+v", logs.All())
+	}
+	if _, ok := e[0].Fields["resp"]; ok {
+		t.Errorf("expect no resp, got %+v", e[0])
+	}
+}
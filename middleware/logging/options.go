@@ -0,0 +1,149 @@
+package logging
+
+import (
+	"github.com/go-cinch/common/log"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric"
+)
+
+type Options struct {
+	successLevel     log.Level
+	clientErrorLevel log.Level
+	serverErrorLevel log.Level
+	slow             int
+	slowLevel        log.Level
+	skips            map[string]struct{}
+	args             bool
+	reply            bool
+	argsKeys         []string
+	replyKeys        []string
+	redactor         *log.Redactor
+	maxLength        int
+	headers          []string
+}
+
+// WithLevel log level by outcome, success / 4xx / 5xx, default info / warn / error
+func WithLevel(success, clientError, serverError log.Level) func(*Options) {
+	return func(options *Options) {
+		ops := getOptionsOrSetDefault(options)
+		ops.successLevel = success
+		ops.clientErrorLevel = clientError
+		ops.serverErrorLevel = serverError
+	}
+}
+
+// WithSlow request slower than milli is marked with slow=true and logged at least at level
+func WithSlow(milli int, level log.Level) func(*Options) {
+	return func(options *Options) {
+		if milli > 0 {
+			getOptionsOrSetDefault(options).slow = milli
+			getOptionsOrSetDefault(options).slowLevel = level
+		}
+	}
+}
+
+// WithSkip operations not logged, such as /grpc.health.v1.Health/Check
+func WithSkip(operation ...string) func(*Options) {
+	return func(options *Options) {
+		for _, item := range operation {
+			getOptionsOrSetDefault(options).skips[item] = struct{}{}
+		}
+	}
+}
+
+// WithArgs log args or not, default true
+func WithArgs(flag bool) func(*Options) {
+	return func(options *Options) {
+		getOptionsOrSetDefault(options).args = flag
+	}
+}
+
+// WithReply log reply or not, default true
+func WithReply(flag bool) func(*Options) {
+	return func(options *Options) {
+		getOptionsOrSetDefault(options).reply = flag
+	}
+}
+
+// WithArgsKeys only log these fields of args, name of go, json or proto field
+func WithArgsKeys(keys ...string) func(*Options) {
+	return func(options *Options) {
+		getOptionsOrSetDefault(options).argsKeys = append(getOptionsOrSetDefault(options).argsKeys, keys...)
+	}
+}
+
+// WithReplyKeys only log these fields of reply, name of go, json or proto field
+func WithReplyKeys(keys ...string) func(*Options) {
+	return func(options *Options) {
+		getOptionsOrSetDefault(options).replyKeys = append(getOptionsOrSetDefault(options).replyKeys, keys...)
+	}
+}
+
+// WithRedactor mask sensitive fields of args, reply, error and headers
+func WithRedactor(r *log.Redactor) func(*Options) {
+	return func(options *Options) {
+		getOptionsOrSetDefault(options).redactor = r
+	}
+}
+
+// WithMaxLength truncate args, reply and error longer than length, 0 means no limit, default 500
+func WithMaxLength(length int) func(*Options) {
+	return func(options *Options) {
+		if length >= 0 {
+			getOptionsOrSetDefault(options).maxLength = length
+		}
+	}
+}
+
+// WithHeader log these request headers, field key is header.<lower case key>
+func WithHeader(keys ...string) func(*Options) {
+	return func(options *Options) {
+		getOptionsOrSetDefault(options).headers = append(getOptionsOrSetDefault(options).headers, keys...)
+	}
+}
+
+func getOptionsOrSetDefault(options *Options) *Options {
+	if options == nil {
+		return &Options{
+			successLevel:     log.InfoLevel,
+			clientErrorLevel: log.WarnLevel,
+			serverErrorLevel: log.ErrorLevel,
+			slowLevel:        log.WarnLevel,
+			skips:            make(map[string]struct{}),
+			args:             true,
+			reply:            true,
+			maxLength:        500,
+		}
+	}
+	return options
+}
+
+type RecoveryOptions struct {
+	handlers      []RecoveryHandler
+	meterProvider metric.MeterProvider
+}
+
+// WithRecoveryHandler called after panic is recovered and logged, such as report to an error tracker
+func WithRecoveryHandler(h ...RecoveryHandler) func(*RecoveryOptions) {
+	return func(options *RecoveryOptions) {
+		getRecoveryOptionsOrSetDefault(options).handlers = append(getRecoveryOptionsOrSetDefault(options).handlers, h...)
+	}
+}
+
+// WithRecoveryMeterProvider meter provider of panic counter, default otel.GetMeterProvider()
+func WithRecoveryMeterProvider(provider metric.MeterProvider) func(*RecoveryOptions) {
+	return func(options *RecoveryOptions) {
+		if provider != nil {
+			getRecoveryOptionsOrSetDefault(options).meterProvider = provider
+		}
+	}
+}
+
+func getRecoveryOptionsOrSetDefault(options *RecoveryOptions) *RecoveryOptions {
+	if options == nil {
+		return &RecoveryOptions{
+			meterProvider: otel.GetMeterProvider(),
+		}
+	}
+	return options
+}
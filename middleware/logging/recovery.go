@@ -0,0 +1,99 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+
+	"github.com/go-cinch/common/constant"
+	"github.com/go-cinch/common/log"
+	"github.com/go-cinch/common/middleware/i18n"
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/transport"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+const (
+	// PanicKey field of panic value
+	PanicKey = "panic"
+	// StackKey field of panic stack
+	StackKey = "stack"
+	// PanicMetric counter of recovered panics, attributes are kind and operation
+	PanicMetric = "server.panics"
+
+	scope = "github.com/go-cinch/common/middleware/logging"
+)
+
+// RecoveryHandler called with panic value e and stack of the goroutine
+type RecoveryHandler func(ctx context.Context, req, e interface{}, stack string)
+
+// Recovery recover panic of handler, log it with stack at error level,
+// count it and return an internal error translated by i18n middleware.
+// put it after Server, the request is also logged by Server with the returned error
+func Recovery(options ...func(*RecoveryOptions)) middleware.Middleware {
+	r := newRecovery(options...)
+	return func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (reply interface{}, err error) {
+			defer func() {
+				if e := recover(); e != nil {
+					err = r.recover(ctx, req, e)
+				}
+			}()
+			return handler(ctx, req)
+		}
+	}
+}
+
+type recovery struct {
+	ops    RecoveryOptions
+	panics metric.Int64Counter
+}
+
+func newRecovery(options ...func(*RecoveryOptions)) *recovery {
+	ops := getRecoveryOptionsOrSetDefault(nil)
+	for _, f := range options {
+		f(ops)
+	}
+	r := &recovery{
+		ops: *ops,
+	}
+	// counter is optional, panic is still recovered if meter failed
+	r.panics, _ = ops.meterProvider.Meter(scope).Int64Counter(
+		PanicMetric,
+		metric.WithDescription("count of panics recovered by server"),
+		metric.WithUnit("{panic}"),
+	)
+	return r
+}
+
+func (r *recovery) recover(ctx context.Context, req, e interface{}) error {
+	buf := make([]byte, 64<<10) //nolint:gomnd
+	n := runtime.Stack(buf, false)
+	stack := string(buf[:n])
+
+	var kind, operation string
+	if info, ok := transport.FromServerContext(ctx); ok {
+		kind = info.Kind().String()
+		operation = info.Operation()
+	}
+	log.
+		WithContext(ctx).
+		WithFields(log.Fields{
+			"kind":   kind,
+			PanicKey: fmt.Sprintf("%v", e),
+			StackKey: stack,
+		}).
+		Error(operation)
+	if r.panics != nil {
+		r.panics.Add(ctx, 1, metric.WithAttributes(
+			attribute.String("kind", kind),
+			attribute.String("operation", operation),
+		))
+	}
+	for _, h := range r.ops.handlers {
+		h(ctx, req, e, stack)
+	}
+	return errors.InternalServer(constant.InternalError, i18n.FromContext(ctx).T(constant.InternalError))
+}
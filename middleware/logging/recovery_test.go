@@ -0,0 +1,88 @@
+package logging
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/go-cinch/common/constant"
+	"github.com/go-cinch/common/log"
+	"github.com/go-cinch/common/log/logtest"
+	"github.com/go-kratos/kratos/v2/errors"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestRecovery(t *testing.T) {
+	logs := logtest.Swap(t)
+	reader := sdkmetric.NewManualReader()
+	var reported interface{}
+	m := Recovery(
+		WithRecoveryMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))),
+		WithRecoveryHandler(func(ctx context.Context, req, e interface{}, stack string) {
+			reported = e
+		}),
+	)
+	h := Server()(m(func(context.Context, interface{}) (interface{}, error) {
+		panic("something wrong")
+	}))
+	_, err := h(newServerContext("/user/create"), nil)
+
+	se := errors.FromError(err)
+	if se.Code != 500 || se.Reason != constant.InternalError || se.Message != constant.InternalError {
+		t.Errorf("expect internal error, got %v", err)
+	}
+	if reported != "something wrong" {
+		t.Errorf("expect handler called, got %v", reported)
+	}
+
+	// one line of panic with stack, one line of request
+	entries := logs.FilterLevel(log.ErrorLevel).All()
+	if len(entries) != 2 {
+		t.Fatalf("expect 2 error lines, got %+v", logs.All())
+	}
+	stack, _ := entries[0].Fields[StackKey].(string)
+	if entries[0].Fields[PanicKey] != "something wrong" || !strings.Contains(stack, "recovery_test.go") {
+		t.Errorf("expect panic and stack, got %+v", entries[0])
+	}
+	if entries[1].Fields["reason"] != constant.InternalError {
+		t.Errorf("expect request logged with error, got %+v", entries[1])
+	}
+
+	var rm metricdata.ResourceMetrics
+	err = reader.Collect(context.Background(), &rm)
+	if err != nil || len(rm.ScopeMetrics) != 1 || len(rm.ScopeMetrics[0].Metrics) != 1 {
+		t.Fatalf("expect panic metric, got %+v %v", rm, err)
+	}
+	sum, ok := rm.ScopeMetrics[0].Metrics[0].Data.(metricdata.Sum[int64])
+	if !ok || len(sum.DataPoints) != 1 || sum.DataPoints[0].Value != 1 {
+		t.Errorf("expect 1 panic, got %+v", rm.ScopeMetrics[0].Metrics[0].Data)
+	}
+}
+
+func TestServer_recover(t *testing.T) {
+	logs := logtest.Swap(t)
+	_, err := Server()(func(context.Context, interface{}) (interface{}, error) {
+		panic("something wrong")
+	})(newServerContext("/user/create"), nil)
+	if errors.FromError(err).Reason != constant.InternalError {
+		t.Errorf("expect internal error, got %v", err)
+	}
+	if logs.FilterFieldKey(StackKey).Len() != 1 {
+		t.Errorf("expect panic logged, got %+v", logs.All())
+	}
+}
+
+func TestServer_recoverSkipped(t *testing.T) {
+	logs := logtest.Swap(t)
+	_, err := Server(WithSkip("/grpc.health.v1.Health/Check"))(func(context.Context, interface{}) (interface{}, error) {
+		panic("something wrong")
+	})(newServerContext("/grpc.health.v1.Health/Check"), nil)
+	if errors.FromError(err).Reason != constant.InternalError {
+		t.Errorf("expect internal error, got %v", err)
+	}
+	// only the panic is logged, the request line is skipped
+	if logs.Len() != 1 || logs.FilterFieldKey(StackKey).Len() != 1 {
+		t.Errorf("expect panic logged only, got %+v", logs.All())
+	}
+}
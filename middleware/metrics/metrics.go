@@ -0,0 +1,199 @@
+package metrics
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-cinch/common/plugins/gorm/tenant/v2"
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/transport"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+const (
+	// RequestsMetric count of requests, name is prefixed with server. or client.
+	RequestsMetric = "requests"
+	// DurationMetric latency histogram in seconds
+	DurationMetric = "duration"
+	// ActiveMetric in-flight requests
+	ActiveMetric = "active_requests"
+	// ErrorsMetric count of failed requests with reason
+	ErrorsMetric = "errors"
+
+	KindKey      = "kind"
+	OperationKey = "operation"
+	TenantKey    = "tenant"
+	CodeKey      = "code"
+	ReasonKey    = "reason"
+	// OtherValue attribute value after max distinct values reached
+	OtherValue = "other"
+
+	scope = "github.com/go-cinch/common/middleware/metrics"
+)
+
+// Server is an server metrics middleware.
+func Server(options ...func(*Options)) middleware.Middleware {
+	r := newRecorder("server", options...)
+	return func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (reply interface{}, err error) {
+			info, ok := transport.FromServerContext(ctx)
+			if !ok {
+				return handler(ctx, req)
+			}
+			return r.record(ctx, info, req, handler)
+		}
+	}
+}
+
+// Client is an client metrics middleware, instruments are prefixed with client.
+func Client(options ...func(*Options)) middleware.Middleware {
+	r := newRecorder("client", options...)
+	return func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (reply interface{}, err error) {
+			info, ok := transport.FromClientContext(ctx)
+			if !ok {
+				return handler(ctx, req)
+			}
+			return r.record(ctx, info, req, handler)
+		}
+	}
+}
+
+type recorder struct {
+	ops      Options
+	requests metric.Int64Counter
+	duration metric.Float64Histogram
+	active   metric.Int64UpDownCounter
+	errors   metric.Int64Counter
+	guard    *guard
+}
+
+func newRecorder(side string, options ...func(*Options)) *recorder {
+	ops := getOptionsOrSetDefault(nil)
+	for _, f := range options {
+		f(ops)
+	}
+	meter := ops.meterProvider.Meter(scope)
+	r := &recorder{
+		ops:   *ops,
+		guard: newGuard(ops.maxValues),
+	}
+	var err error
+	r.requests, err = meter.Int64Counter(
+		strings.Join([]string{side, RequestsMetric}, "."),
+		metric.WithDescription("count of requests"),
+		metric.WithUnit("{request}"),
+	)
+	otelHandle(err)
+	r.duration, err = meter.Float64Histogram(
+		strings.Join([]string{side, DurationMetric}, "."),
+		metric.WithDescription("latency of requests"),
+		metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(ops.buckets...),
+	)
+	otelHandle(err)
+	r.active, err = meter.Int64UpDownCounter(
+		strings.Join([]string{side, ActiveMetric}, "."),
+		metric.WithDescription("count of in-flight requests"),
+		metric.WithUnit("{request}"),
+	)
+	otelHandle(err)
+	r.errors, err = meter.Int64Counter(
+		strings.Join([]string{side, ErrorsMetric}, "."),
+		metric.WithDescription("count of failed requests by reason"),
+		metric.WithUnit("{request}"),
+	)
+	otelHandle(err)
+	return r
+}
+
+func (r *recorder) record(ctx context.Context, info transport.Transporter, req interface{}, handler middleware.Handler) (reply interface{}, err error) {
+	operation := info.Operation()
+	if _, ok := r.ops.skips[operation]; ok {
+		return handler(ctx, req)
+	}
+	attrs := []attribute.KeyValue{
+		attribute.String(KindKey, info.Kind().String()),
+		attribute.String(OperationKey, r.guard.value(OperationKey, operation)),
+	}
+	if r.ops.tenant {
+		if id := tenant.FromContext(ctx); id != "" {
+			attrs = append(attrs, attribute.String(TenantKey, r.guard.value(TenantKey, id)))
+		}
+	}
+	active := metric.WithAttributeSet(attribute.NewSet(attrs...))
+	r.active.Add(ctx, 1, active)
+	startTime := time.Now()
+	defer func() {
+		r.active.Add(ctx, -1, active)
+	}()
+
+	reply, err = handler(ctx, req)
+
+	code := 200
+	var reason string
+	if se := errors.FromError(err); se != nil {
+		code = int(se.Code)
+		reason = se.Reason
+	}
+	attrs = append(attrs, attribute.String(CodeKey, strconv.Itoa(code)))
+	set := metric.WithAttributeSet(attribute.NewSet(attrs...))
+	r.requests.Add(ctx, 1, set)
+	r.duration.Record(ctx, time.Since(startTime).Seconds(), set)
+	if err != nil {
+		attrs = append(attrs, attribute.String(ReasonKey, r.guard.value(ReasonKey, reason)))
+		r.errors.Add(ctx, 1, metric.WithAttributeSet(attribute.NewSet(attrs...)))
+	}
+	return
+}
+
+// guard limit distinct values of each attribute
+type guard struct {
+	lock   sync.RWMutex
+	max    int
+	values map[string]map[string]struct{}
+}
+
+func newGuard(maxValues int) *guard {
+	return &guard{
+		max:    maxValues,
+		values: make(map[string]map[string]struct{}),
+	}
+}
+
+// value return v if it's seen or there is room for it, otherwise OtherValue
+func (g *guard) value(key, v string) string {
+	g.lock.RLock()
+	_, ok := g.values[key][v]
+	g.lock.RUnlock()
+	if ok {
+		return v
+	}
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	vs, ok := g.values[key]
+	if !ok {
+		vs = make(map[string]struct{})
+		g.values[key] = vs
+	}
+	if _, ok = vs[v]; ok {
+		return v
+	}
+	if len(vs) >= g.max {
+		return OtherValue
+	}
+	vs[v] = struct{}{}
+	return v
+}
+
+func otelHandle(err error) {
+	if err != nil {
+		otel.Handle(err)
+	}
+}
@@ -0,0 +1,112 @@
+package metrics
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-cinch/common/plugins/gorm/tenant/v2"
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/transport"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// grpcTransport only kind and operation are read by recorder, other methods panic
+type grpcTransport struct {
+	transport.Transporter
+	operation string
+}
+
+func (tr grpcTransport) Kind() transport.Kind { return transport.KindGRPC }
+
+func (tr grpcTransport) Operation() string { return tr.operation }
+
+func collect(t *testing.T, reader sdkmetric.Reader) map[string]metricdata.Aggregation {
+	var rm metricdata.ResourceMetrics
+	err := reader.Collect(context.Background(), &rm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs := make(map[string]metricdata.Aggregation)
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			rs[m.Name] = m.Data
+		}
+	}
+	return rs
+}
+
+func TestServer(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	m := Server(
+		WithMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))),
+		WithMaxValues(2),
+		WithSkip("/health"),
+	)
+	h := m(func(ctx context.Context, req interface{}) (interface{}, error) {
+		if req == "fail" {
+			return nil, errors.BadRequest("INVALID", "invalid")
+		}
+		return "ok", nil
+	})
+	ctx := tenant.NewContext(context.Background(), "t1")
+	for _, operation := range []string{"/a", "/b", "/c", "/health"} {
+		_, _ = h(transport.NewServerContext(ctx, grpcTransport{operation: operation}), "ok")
+	}
+	_, _ = h(transport.NewServerContext(ctx, grpcTransport{operation: "/a"}), "fail")
+
+	data := collect(t, reader)
+	requests, _ := data["server.requests"].(metricdata.Sum[int64])
+	counts := make(map[string]int64)
+	for _, item := range requests.DataPoints {
+		operation, _ := item.Attributes.Value(OperationKey)
+		code, _ := item.Attributes.Value(CodeKey)
+		tenantID, _ := item.Attributes.Value(TenantKey)
+		if tenantID.AsString() != "t1" {
+			t.Errorf("expect tenant, got %v", item.Attributes)
+		}
+		counts[operation.AsString()+" "+code.AsString()] += item.Value
+	}
+	// /c is over max values, /health is skipped
+	if counts["/a 200"] != 1 || counts["/b 200"] != 1 || counts[OtherValue+" 200"] != 1 || counts["/a 400"] != 1 || len(counts) != 4 {
+		t.Errorf("unexpected requests %v", counts)
+	}
+
+	errs, _ := data["server.errors"].(metricdata.Sum[int64])
+	if len(errs.DataPoints) != 1 {
+		t.Fatalf("expect 1 error point, got %+v", errs)
+	}
+	if reason, _ := errs.DataPoints[0].Attributes.Value(ReasonKey); reason.AsString() != "INVALID" {
+		t.Errorf("expect reason, got %v", errs.DataPoints[0].Attributes)
+	}
+
+	duration, _ := data["server.duration"].(metricdata.Histogram[float64])
+	if len(duration.DataPoints) != 4 {
+		t.Errorf("expect 4 duration points, got %d", len(duration.DataPoints))
+	}
+
+	active, _ := data["server.active_requests"].(metricdata.Sum[int64])
+	for _, item := range active.DataPoints {
+		if item.Value != 0 {
+			t.Errorf("expect no in-flight request, got %v", item)
+		}
+	}
+}
+
+func TestClient(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	m := Client(WithMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))), WithTenant(false))
+	ctx := transport.NewClientContext(tenant.NewContext(context.Background(), "t1"), grpcTransport{operation: "/a"})
+	_, _ = m(func(context.Context, interface{}) (interface{}, error) {
+		return "ok", nil
+	})(ctx, nil)
+
+	requests, _ := collect(t, reader)["client.requests"].(metricdata.Sum[int64])
+	if len(requests.DataPoints) != 1 || requests.DataPoints[0].Value != 1 {
+		t.Fatalf("expect 1 client request, got %+v", requests)
+	}
+	if requests.DataPoints[0].Attributes.HasValue(attribute.Key(TenantKey)) {
+		t.Errorf("expect no tenant, got %v", requests.DataPoints[0].Attributes)
+	}
+}
@@ -0,0 +1,70 @@
+package metrics
+
+import (
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric"
+)
+
+type Options struct {
+	meterProvider metric.MeterProvider
+	skips         map[string]struct{}
+	buckets       []float64
+	maxValues     int
+	tenant        bool
+}
+
+// WithMeterProvider meter provider of instruments, default otel.GetMeterProvider()
+func WithMeterProvider(provider metric.MeterProvider) func(*Options) {
+	return func(options *Options) {
+		if provider != nil {
+			getOptionsOrSetDefault(options).meterProvider = provider
+		}
+	}
+}
+
+// WithSkip operations not recorded, so frequent probes don't drown real traffic
+func WithSkip(operation ...string) func(*Options) {
+	return func(options *Options) {
+		for _, item := range operation {
+			getOptionsOrSetDefault(options).skips[item] = struct{}{}
+		}
+	}
+}
+
+// WithBuckets bucket boundaries of latency histogram in seconds
+func WithBuckets(seconds ...float64) func(*Options) {
+	return func(options *Options) {
+		if len(seconds) > 0 {
+			getOptionsOrSetDefault(options).buckets = seconds
+		}
+	}
+}
+
+// WithMaxValues max distinct values of each attribute, the others are recorded as OtherValue, default 1000
+func WithMaxValues(count int) func(*Options) {
+	return func(options *Options) {
+		if count > 0 {
+			getOptionsOrSetDefault(options).maxValues = count
+		}
+	}
+}
+
+// WithTenant record tenant id of context, default true
+func WithTenant(flag bool) func(*Options) {
+	return func(options *Options) {
+		getOptionsOrSetDefault(options).tenant = flag
+	}
+}
+
+func getOptionsOrSetDefault(options *Options) *Options {
+	if options == nil {
+		return &Options{
+			meterProvider: otel.GetMeterProvider(),
+			skips:         make(map[string]struct{}),
+			buckets:       []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+			maxValues:     1000,
+			tenant:        true,
+		}
+	}
+	return options
+}
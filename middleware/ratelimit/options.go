@@ -0,0 +1,128 @@
+package ratelimit
+
+import (
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+type Algorithm int
+
+const (
+	// SlidingWindow at most limit requests in any window
+	SlidingWindow Algorithm = iota
+	// TokenBucket rate tokens per second, at most burst tokens
+	TokenBucket
+)
+
+type Options struct {
+	redis     redis.UniversalClient
+	prefix    string
+	algorithm Algorithm
+	limit     int
+	window    int
+	rate      int
+	burst     int
+	keys      []KeyFunc
+	skips     map[string]struct{}
+	now       func() time.Time
+}
+
+func WithRedis(rd redis.UniversalClient) func(*Options) {
+	return func(options *Options) {
+		if rd != nil {
+			getOptionsOrSetDefault(options).redis = rd
+		}
+	}
+}
+
+func WithPrefix(prefix string) func(*Options) {
+	return func(options *Options) {
+		if prefix != "" {
+			getOptionsOrSetDefault(options).prefix = prefix
+		}
+	}
+}
+
+// WithSlidingWindow at most limit requests in any window of second, default 100 requests in 60 seconds
+func WithSlidingWindow(limit, second int) func(*Options) {
+	return func(options *Options) {
+		if limit > 0 && second > 0 {
+			ops := getOptionsOrSetDefault(options)
+			ops.algorithm = SlidingWindow
+			ops.limit = limit
+			ops.window = second
+		}
+	}
+}
+
+// WithTokenBucket rate requests per second, burst requests at most
+func WithTokenBucket(rate, burst int) func(*Options) {
+	return func(options *Options) {
+		if rate > 0 && burst > 0 {
+			ops := getOptionsOrSetDefault(options)
+			ops.algorithm = TokenBucket
+			ops.rate = rate
+			ops.burst = burst
+		}
+	}
+}
+
+// WithKey key of limit is joined by result of each fun, default ByOperation and ByIP
+func WithKey(fun ...KeyFunc) func(*Options) {
+	return func(options *Options) {
+		if len(fun) > 0 {
+			getOptionsOrSetDefault(options).keys = fun
+		}
+	}
+}
+
+// WithSkip operations never limited, e.g. probes of load balancer
+func WithSkip(operation ...string) func(*Options) {
+	return func(options *Options) {
+		for _, item := range operation {
+			getOptionsOrSetDefault(options).skips[item] = struct{}{}
+		}
+	}
+}
+
+func getOptionsOrSetDefault(options *Options) *Options {
+	if options == nil {
+		return &Options{
+			prefix:    "ratelimit",
+			algorithm: SlidingWindow,
+			limit:     100,
+			window:    60,
+			keys: []KeyFunc{
+				ByOperation(),
+				ByIP(),
+			},
+			skips: make(map[string]struct{}),
+			now:   time.Now,
+		}
+	}
+	return options
+}
+
+type IPOptions struct {
+	headers []string
+}
+
+// WithTrustedProxyHeaders client ip is read from these headers first, default X-Forwarded-For and X-Real-Ip if no header passed,
+// only use it behind a proxy which overwrites or appends them, otherwise client can forge its ip
+func WithTrustedProxyHeaders(headers ...string) func(*IPOptions) {
+	return func(options *IPOptions) {
+		ops := getIPOptionsOrSetDefault(options)
+		ops.headers = headers
+		if len(headers) == 0 {
+			ops.headers = []string{"X-Forwarded-For", "X-Real-Ip"}
+		}
+	}
+}
+
+func getIPOptionsOrSetDefault(options *IPOptions) *IPOptions {
+	if options == nil {
+		return &IPOptions{}
+	}
+	return options
+}
@@ -0,0 +1,257 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-cinch/common/constant"
+	"github.com/go-cinch/common/jwt"
+	"github.com/go-cinch/common/log"
+	"github.com/go-cinch/common/middleware/i18n"
+	"github.com/go-cinch/common/plugins/gorm/tenant/v2"
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/transport"
+	khttp "github.com/go-kratos/kratos/v2/transport/http"
+	"github.com/redis/go-redis/v9"
+	"google.golang.org/grpc/peer"
+)
+
+// redis lua script, time is passed by caller in millisecond,
+// return {allowed, remaining, reset} and reset is millisecond until the limit is fully or partly released
+const (
+	// sorted set of request time, remove requests out of window then count
+	luaSlidingWindow string = `
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now - window)
+local count = redis.call('ZCARD', KEYS[1])
+local allowed = 0
+if count < limit then
+    redis.call('ZADD', KEYS[1], now, ARGV[4])
+    redis.call('PEXPIRE', KEYS[1], window)
+    count = count + 1
+    allowed = 1
+end
+local oldest = redis.call('ZRANGE', KEYS[1], 0, 0, 'WITHSCORES')
+local reset = 0
+if oldest[2] ~= nil then
+    reset = tonumber(oldest[2]) + window - now
+end
+return {allowed, limit - count, reset}
+`
+	// hash of tokens and last refill time
+	luaTokenBucket string = `
+local now = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local burst = tonumber(ARGV[3])
+local data = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(data[1])
+local ts = tonumber(data[2])
+if tokens == nil or ts == nil then
+    tokens = burst
+    ts = now
+end
+tokens = math.min(burst, tokens + math.max(0, now - ts) * rate / 1000)
+local allowed = 0
+local reset = 0
+if tokens >= 1 then
+    tokens = tokens - 1
+    allowed = 1
+    reset = math.ceil((burst - tokens) * 1000 / rate)
+else
+    reset = math.ceil((1 - tokens) * 1000 / rate)
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', now)
+redis.call('PEXPIRE', KEYS[1], math.ceil(burst * 1000 / rate))
+return {allowed, math.floor(tokens), reset}
+`
+)
+
+var (
+	slidingWindowScript = redis.NewScript(luaSlidingWindow)
+	tokenBucketScript   = redis.NewScript(luaTokenBucket)
+)
+
+const (
+	HeaderLimit      = "X-RateLimit-Limit"
+	HeaderRemaining  = "X-RateLimit-Remaining"
+	HeaderReset      = "X-RateLimit-Reset"
+	HeaderRetryAfter = "Retry-After"
+)
+
+// KeyFunc part of limit key from request context
+type KeyFunc func(ctx context.Context) string
+
+// Result of one request
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset time until the limit is released, it's also retry after when not allowed
+	Reset time.Duration
+}
+
+type RateLimit struct {
+	ops Options
+}
+
+func New(options ...func(*Options)) *RateLimit {
+	ops := getOptionsOrSetDefault(nil)
+	for _, f := range options {
+		f(ops)
+	}
+	return &RateLimit{ops: *ops}
+}
+
+// Allow take one request of key, clocks of callers should be synchronized
+func (r *RateLimit) Allow(ctx context.Context, key string) (rp Result, err error) {
+	if r.ops.redis == nil {
+		log.WithContext(ctx).Warn("please enable redis, otherwise the rate limit is invalid")
+		rp.Allowed = true
+		return
+	}
+	now := r.ops.now().UnixMilli()
+	var res []int64
+	switch r.ops.algorithm {
+	case TokenBucket:
+		rp.Limit = r.ops.burst
+		res, err = tokenBucketScript.Run(
+			ctx,
+			r.ops.redis,
+			[]string{strings.Join([]string{r.ops.prefix, "tb", key}, "_")},
+			now,
+			r.ops.rate,
+			r.ops.burst,
+		).Int64Slice()
+	default:
+		rp.Limit = r.ops.limit
+		res, err = slidingWindowScript.Run(
+			ctx,
+			r.ops.redis,
+			[]string{strings.Join([]string{r.ops.prefix, "sw", key}, "_")},
+			now,
+			r.ops.window*1000,
+			r.ops.limit,
+			strings.Join([]string{strconv.FormatInt(now, 10), strconv.FormatUint(rand.Uint64(), 36)}, "-"),
+		).Int64Slice()
+	}
+	if err != nil || len(res) != 3 {
+		return
+	}
+	rp.Allowed = res[0] == 1
+	rp.Remaining = int(res[1])
+	rp.Reset = time.Duration(res[2]) * time.Millisecond
+	return
+}
+
+// Server is an server rate limit middleware,
+// request over limit returns TooManyRequests error translated by i18n middleware, redis error does not block request
+func Server(options ...func(*Options)) middleware.Middleware {
+	r := New(options...)
+	return func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (rp interface{}, err error) {
+			tr, ok := transport.FromServerContext(ctx)
+			if !ok {
+				return handler(ctx, req)
+			}
+			if _, ok = r.ops.skips[tr.Operation()]; ok {
+				return handler(ctx, req)
+			}
+			keys := make([]string, len(r.ops.keys))
+			for i, f := range r.ops.keys {
+				keys[i] = f(ctx)
+			}
+			res, e := r.Allow(ctx, strings.Join(keys, ":"))
+			if e != nil {
+				log.WithContext(ctx).WithError(e).Warn("rate limit failed")
+				return handler(ctx, req)
+			}
+			header := tr.ReplyHeader()
+			header.Set(HeaderLimit, strconv.Itoa(res.Limit))
+			header.Set(HeaderRemaining, strconv.Itoa(res.Remaining))
+			header.Set(HeaderReset, seconds(res.Reset))
+			if !res.Allowed {
+				header.Set(HeaderRetryAfter, seconds(res.Reset))
+				err = errors.New(http.StatusTooManyRequests, constant.TooManyRequests, i18n.FromContext(ctx).T(constant.TooManyRequests))
+				return
+			}
+			return handler(ctx, req)
+		}
+	}
+}
+
+// ByOperation limit each operation
+func ByOperation() KeyFunc {
+	return func(ctx context.Context) (rp string) {
+		if tr, ok := transport.FromServerContext(ctx); ok {
+			rp = tr.Operation()
+		}
+		return
+	}
+}
+
+// ByIP limit each client ip, peer address of the connection is used,
+// proxy headers are only used if they are trusted by WithTrustedProxyHeaders
+func ByIP(options ...func(*IPOptions)) KeyFunc {
+	ops := getIPOptionsOrSetDefault(nil)
+	for _, f := range options {
+		f(ops)
+	}
+	return func(ctx context.Context) (rp string) {
+		if tr, ok := transport.FromServerContext(ctx); ok {
+			for _, item := range ops.headers {
+				if rp = forwarded(tr.RequestHeader().Get(item)); rp != "" {
+					return
+				}
+			}
+		}
+		if req, ok := khttp.RequestFromServerContext(ctx); ok {
+			rp = host(req.RemoteAddr)
+			return
+		}
+		if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+			rp = host(p.Addr.String())
+		}
+		return
+	}
+}
+
+// ByUser limit each user code of jwt, anonymous users share the same limit, combine it with ByIP if needed
+func ByUser() KeyFunc {
+	return func(ctx context.Context) string {
+		return jwt.FromServerContext(ctx).Code
+	}
+}
+
+// ByTenant limit each tenant id
+func ByTenant() KeyFunc {
+	return func(ctx context.Context) string {
+		return tenant.FromContext(ctx)
+	}
+}
+
+func host(addr string) string {
+	if h, _, err := net.SplitHostPort(addr); err == nil {
+		return h
+	}
+	return addr
+}
+
+// forwarded the last address of header, it's appended by the nearest proxy and can't be forged by client
+func forwarded(v string) string {
+	items := strings.Split(v, ",")
+	return strings.TrimSpace(items[len(items)-1])
+}
+
+// seconds round up d to seconds
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
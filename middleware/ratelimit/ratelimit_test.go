@@ -0,0 +1,157 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/go-cinch/common/constant"
+	"github.com/go-cinch/common/jwt"
+	"github.com/go-cinch/common/mock"
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/transport"
+	"github.com/redis/go-redis/v9"
+	"google.golang.org/grpc/peer"
+)
+
+// header single value header, enough for limit headers and proxy headers
+type header map[string]string
+
+func (h header) Get(key string) string { return h[key] }
+
+func (h header) Set(key, value string) { h[key] = value }
+
+func (h header) Add(key, value string) { h[key] = value }
+
+func (h header) Keys() []string { return nil }
+
+func (h header) Values(key string) []string { return []string{h[key]} }
+
+// httpTransport request header is read by ByIP, reply header is written by Server
+type httpTransport struct {
+	transport.Transporter
+	request header
+	reply   header
+}
+
+func newTransport() *httpTransport {
+	return &httpTransport{request: header{}, reply: header{}}
+}
+
+func (tr *httpTransport) Operation() string { return "/user/create" }
+
+func (tr *httpTransport) RequestHeader() transport.Header { return tr.request }
+
+func (tr *httpTransport) ReplyHeader() transport.Header { return tr.reply }
+
+func newRedis(t *testing.T) redis.UniversalClient {
+	host, port, err := mock.NewRedis()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return redis.NewClient(&redis.Options{
+		Addr: fmt.Sprintf("%s:%d", host, port),
+	})
+}
+
+// clock set now of r, return a func to move it forward
+func clock(r *RateLimit) func(d time.Duration) {
+	now := time.Now()
+	r.ops.now = func() time.Time {
+		return now
+	}
+	return func(d time.Duration) {
+		now = now.Add(d)
+	}
+}
+
+func TestRateLimit_slidingWindow(t *testing.T) {
+	r := New(WithRedis(newRedis(t)), WithSlidingWindow(2, 1))
+	forward := clock(r)
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		res, err := r.Allow(ctx, "k")
+		if err != nil || !res.Allowed || res.Remaining != 1-i {
+			t.Fatalf("expect allowed, got %+v %v", res, err)
+		}
+		forward(400 * time.Millisecond)
+	}
+	res, err := r.Allow(ctx, "k")
+	if err != nil || res.Allowed || res.Reset != 200*time.Millisecond {
+		t.Fatalf("expect denied until the first request out of window, got %+v %v", res, err)
+	}
+	forward(200 * time.Millisecond)
+	res, err = r.Allow(ctx, "k")
+	if err != nil || !res.Allowed {
+		t.Fatalf("expect allowed after window slides, got %+v %v", res, err)
+	}
+}
+
+func TestRateLimit_tokenBucket(t *testing.T) {
+	r := New(WithRedis(newRedis(t)), WithTokenBucket(2, 2))
+	forward := clock(r)
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		res, err := r.Allow(ctx, "k")
+		if err != nil || !res.Allowed {
+			t.Fatalf("expect burst allowed, got %+v %v", res, err)
+		}
+	}
+	res, err := r.Allow(ctx, "k")
+	if err != nil || res.Allowed || res.Reset != 500*time.Millisecond {
+		t.Fatalf("expect denied until one token refilled, got %+v %v", res, err)
+	}
+	forward(500 * time.Millisecond)
+	res, err = r.Allow(ctx, "k")
+	if err != nil || !res.Allowed || res.Remaining != 0 {
+		t.Fatalf("expect allowed after refill, got %+v %v", res, err)
+	}
+}
+
+func TestServer(t *testing.T) {
+	m := Server(WithRedis(newRedis(t)), WithSlidingWindow(1, 60), WithKey(ByOperation(), ByUser()))
+	h := m(func(context.Context, interface{}) (interface{}, error) {
+		return "ok", nil
+	})
+	call := func(code string) (*httpTransport, error) {
+		tr := newTransport()
+		ctx := jwt.NewServerContextByUser(context.Background(), jwt.User{Code: code})
+		_, err := h(transport.NewServerContext(ctx, tr), nil)
+		return tr, err
+	}
+
+	tr, err := call("u1")
+	if err != nil || tr.reply.Get(HeaderLimit) != "1" || tr.reply.Get(HeaderRemaining) != "0" {
+		t.Fatalf("expect allowed with headers, got %v %v", tr.reply, err)
+	}
+	tr, err = call("u1")
+	se := errors.FromError(err)
+	if se.Code != http.StatusTooManyRequests || se.Reason != constant.TooManyRequests || tr.reply.Get(HeaderRetryAfter) != "60" {
+		t.Fatalf("expect too many requests, got %v %v", tr.reply, err)
+	}
+	// limit of another user
+	_, err = call("u2")
+	if err != nil {
+		t.Fatalf("expect allowed, got %v", err)
+	}
+}
+
+func TestByIP(t *testing.T) {
+	tr := newTransport()
+	tr.request["X-Forwarded-For"] = "10.0.0.1, 10.0.0.2"
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.IPv4(10, 0, 0, 3), Port: 8080}})
+	ctx = transport.NewServerContext(ctx, tr)
+	if ip := ByIP()(ctx); ip != "10.0.0.3" {
+		t.Errorf("expect peer ip since headers are not trusted, got %s", ip)
+	}
+	if ip := ByIP(WithTrustedProxyHeaders())(ctx); ip != "10.0.0.2" {
+		t.Errorf("expect ip appended by proxy, got %s", ip)
+	}
+	delete(tr.request, "X-Forwarded-For")
+	if ip := ByIP(WithTrustedProxyHeaders())(ctx); ip != "10.0.0.3" {
+		t.Errorf("expect peer ip without headers, got %s", ip)
+	}
+}
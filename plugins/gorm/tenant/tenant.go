@@ -23,6 +23,22 @@ func ID() kratosLog.Valuer {
 	}
 }
 
+// LogKey tenant id field of LogExtractor
+const LogKey = "tenant_id"
+
+// LogExtractor print tenant id of each line, use it by log.WithExtractor, nothing is printed without tenant
+func LogExtractor() log.Extractor {
+	return func(ctx context.Context) log.Fields {
+		id := FromContext(ctx)
+		if id == "" {
+			return nil
+		}
+		return log.Fields{
+			LogKey: id,
+		}
+	}
+}
+
 type tenantCtx struct{}
 
 func NewContext(ctx context.Context, id string) context.Context {
@@ -0,0 +1,176 @@
+package rabbit
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-cinch/common/log"
+	"github.com/pkg/errors"
+	"github.com/streadway/amqp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ConsumeBatch start consuming in background, deliveries are collected until size reached
+// or maxWait(milli second) passed since the first one of batch.
+// handler returns ack flag of each delivery(missing flag means nack), continuous deliveries with the same result
+// are acked/nacked together with multiple=true. qos prefetch count is at least size
+func (qu *Queue) ConsumeBatch(size, maxWait int, handler func(context.Context, []amqp.Delivery) []bool, options ...func(*ConsumeOptions)) (co *Consumer, err error) {
+	if size < 1 {
+		err = errors.Errorf("minimum size is 1")
+		return
+	}
+	if maxWait < 1 {
+		err = errors.Errorf("minimum max wait is 1")
+		return
+	}
+	if handler == nil {
+		err = errors.Errorf("handler is nil")
+		return
+	}
+	co = qu.beforeConsume(options...)
+	if co.Error != nil {
+		err = errors.WithStack(co.Error)
+		return
+	}
+	if co.ops.qosPrefetchCount < size {
+		co.ops.qosPrefetchCount = size
+	}
+	go co.loop(func(ch *amqp.Channel) error {
+		return co.consumeBatch(ch, size, time.Duration(maxWait)*time.Millisecond, handler)
+	})
+	return
+}
+
+// consumeBatch receive deliveries and flush them by size or max wait until stopped(return nil) or channel closed
+func (co *Consumer) consumeBatch(ch *amqp.Channel, size int, maxWait time.Duration, handler func(context.Context, []amqp.Delivery) []bool) (err error) {
+	err = ch.Qos(co.ops.qosPrefetchCount, 0, false)
+	if err != nil {
+		return
+	}
+	ds, err := ch.Consume(
+		co.q,
+		co.tag,
+		co.ops.autoAck,
+		co.ops.exclusive,
+		false,
+		co.ops.noWait,
+		co.ops.args,
+	)
+	if err != nil {
+		return
+	}
+	ctx := co.qu.ex.rb.ops.ctx
+	batch := make([]amqp.Delivery, 0, size)
+	timer := time.NewTimer(maxWait)
+	timer.Stop()
+	defer timer.Stop()
+	flush := func() {
+		timer.Stop()
+		co.deliverBatch(ctx, batch, handler)
+		batch = make([]amqp.Delivery, 0, size)
+	}
+	for {
+		select {
+		case <-co.stop:
+			// batch is not handled, give it back
+			if len(batch) > 0 && !co.ops.autoAck {
+				_ = batch[len(batch)-1].Nack(true, true)
+			}
+			_ = ch.Cancel(co.tag, false)
+			return
+		case d, ok := <-ds:
+			if !ok {
+				err = errors.Errorf("delivery channel closed")
+				return
+			}
+			batch = append(batch, d)
+			if len(batch) == 1 {
+				timer.Reset(maxWait)
+			}
+			if len(batch) >= size {
+				flush()
+			}
+		case <-timer.C:
+			if len(batch) > 0 {
+				flush()
+			}
+		}
+	}
+}
+
+// deliverBatch call handler with links to trace context of each delivery, then ack/nack by results
+func (co *Consumer) deliverBatch(ctx context.Context, ds []amqp.Delivery, handler func(context.Context, []amqp.Delivery) []bool) {
+	rb := co.qu.ex.rb
+	links := make([]trace.Link, 0, len(ds))
+	for _, d := range ds {
+		sc := trace.SpanContextFromContext(rb.extractHeaders(ctx, d.Headers))
+		if sc.IsValid() {
+			links = append(links, trace.Link{SpanContext: sc})
+		}
+	}
+	tr := otel.Tracer("rabbit")
+	ctx, span := tr.Start(ctx, "ConsumeBatch", trace.WithSpanKind(trace.SpanKindConsumer), trace.WithLinks(links...))
+	defer span.End()
+	span.SetAttributes(
+		attribute.String("queue", co.q),
+		attribute.Int("batch.size", len(ds)),
+	)
+	hctx := ctx
+	if co.ops.timeout > 0 {
+		var cancel context.CancelFunc
+		hctx, cancel = context.WithTimeout(ctx, time.Duration(co.ops.timeout)*time.Millisecond)
+		defer cancel()
+	}
+	acks := handler(hctx, ds)
+	if co.ops.autoAck {
+		return
+	}
+	// settle: ack deliveries including retried ones, reject deliveries exceeded max retry
+	settle := make([]action, len(ds))
+	nacked := 0
+	for i, d := range ds {
+		if i < len(acks) && acks[i] {
+			settle[i] = actionAck
+			continue
+		}
+		nacked++
+		settle[i] = actionNack
+		if !co.retryable() {
+			continue
+		}
+		e := co.retry(ctx, d)
+		if e == nil {
+			settle[i] = actionAck
+			continue
+		}
+		if errors.Is(e, errRetryExceeded) {
+			settle[i] = actionReject
+			continue
+		}
+		log.WithContext(ctx).WithError(e).Warn("consume retry failed, nack it")
+	}
+	if nacked > 0 {
+		span.SetStatus(codes.Error, "handler nack")
+	}
+	// every delivery before a run is already settled, so multiple only settles the run
+	for i := 0; i < len(ds); i++ {
+		if i < len(ds)-1 && settle[i] == settle[i+1] {
+			continue
+		}
+		var e error
+		switch settle[i] {
+		case actionAck:
+			e = ds[i].Ack(true)
+		case actionReject:
+			e = ds[i].Nack(true, false)
+		default:
+			e = ds[i].Nack(true, co.ops.nackRequeue)
+		}
+		if e != nil {
+			log.WithContext(ctx).WithError(e).Error("consume batch ack failed")
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package rabbit
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-cinch/common/rabbit/rabbittest"
+	"github.com/streadway/amqp"
+)
+
+func TestQueue_ConsumeBatch(t *testing.T) {
+	s, err := rabbittest.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	rb := New(s.URI(), WithMaxConnection(1), WithMaxChannel(2))
+	if rb.Error != nil {
+		t.Fatal(rb.Error)
+	}
+	defer rb.Close()
+	ex := rb.Exchange(WithExchangeName("ex1"))
+	qu := ex.Queue(WithQueueName("q1"), WithQueueRouteKeys("rt1"))
+	if qu.Error != nil {
+		t.Fatal(qu.Error)
+	}
+	for _, body := range []string{"1", "2", "3", "4", "5"} {
+		err = ex.PublishByte([]byte(body), WithPublishRouteKey("rt1"), WithPublishConfirm(true))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var lock sync.Mutex
+	var sizes []int
+	done := make(chan struct{}, 2)
+	co, err := qu.ConsumeBatch(
+		3,
+		100,
+		func(ctx context.Context, ds []amqp.Delivery) []bool {
+			lock.Lock()
+			defer lock.Unlock()
+			sizes = append(sizes, len(ds))
+			done <- struct{}{}
+			// nack the second one without requeue
+			return []bool{true, false, true}
+		},
+		WithConsumeNackRequeue(false),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		select {
+		case <-done:
+		case <-time.After(3 * time.Second):
+			t.Fatal("expect 2 batches")
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	if err = co.Stop(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	if len(sizes) != 2 || sizes[0] != 3 || sizes[1] != 2 {
+		t.Errorf("expect batch sizes [3 2], got %v", sizes)
+		return
+	}
+	// all deliveries are settled, nothing is requeued after consumer stopped
+	if n := s.QueueLen("q1"); n != 0 {
+		t.Errorf("expect queue is empty, got %d", n)
+	}
+}
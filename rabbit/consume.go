@@ -2,52 +2,223 @@ package rabbit
 
 import (
 	"context"
+	"strings"
 	"sync/atomic"
 	"time"
 
 	"github.com/go-cinch/common/log"
-	"github.com/houseofcat/turbocookedrabbit/v2/pkg/tcr"
+	"github.com/google/uuid"
 	"github.com/pkg/errors"
 	"github.com/streadway/amqp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
-type Consume struct {
-	ops      ConsumeOptions
-	q        string
-	consumer *tcr.Consumer
-	Error    error
+// action decides how to confirm a handled delivery
+type action int
+
+const (
+	actionAck action = iota
+	actionNack
+	// actionReject nack without requeue and retry, delivery will be dead-lettered if queue has dead letter exchange
+	actionReject
+)
+
+type deliveryHandler func(context.Context, string, amqp.Delivery) action
+
+func toDeliveryHandler(handler func(context.Context, string, amqp.Delivery) bool) deliveryHandler {
+	return func(ctx context.Context, q string, d amqp.Delivery) action {
+		if handler(ctx, q, d) {
+			return actionAck
+		}
+		return actionNack
+	}
 }
 
-func (qu *Queue) Consume(handler func(context.Context, string, amqp.Delivery) bool, options ...func(*ConsumeOptions)) (err error) {
+type Consumer struct {
+	ops     ConsumeOptions
+	qu      *Queue
+	q       string
+	tag     string
+	stop    chan struct{}
+	done    chan struct{}
+	stopped int32
+	Error   error
+}
+
+// Consume start consuming in background, handler ctx carries the trace context of publisher,
+// use Consumer.Stop to stop it gracefully
+func (qu *Queue) Consume(handler func(context.Context, string, amqp.Delivery) bool, options ...func(*ConsumeOptions)) (co *Consumer, err error) {
 	if handler == nil {
 		err = errors.Errorf("handler is nil")
 		return
 	}
-	co := qu.beforeConsume(options...)
+	co, err = qu.consume(toDeliveryHandler(handler), options...)
+	return
+}
+
+// consume start consumer loop in background
+func (qu *Queue) consume(handler deliveryHandler, options ...func(*ConsumeOptions)) (co *Consumer, err error) {
+	co = qu.beforeConsume(options...)
 	if co.Error != nil {
 		err = errors.WithStack(co.Error)
 		return
 	}
-	co.consumer.StartConsumingWithAction(func(msg *tcr.ReceivedMessage) {
-		d := msg.Delivery
-		a := d.Acknowledger
-		tag := d.DeliveryTag
-		ok := handler(context.Background(), co.q, d)
-		if co.ops.autoAck {
+	go co.loop(func(ch *amqp.Channel) error {
+		return co.consume(ch, handler)
+	})
+	return
+}
+
+// Stop stop receiving deliveries and wait in-flight handler finished or ctx done
+func (co *Consumer) Stop(ctx context.Context) (err error) {
+	if co == nil || co.done == nil {
+		return
+	}
+	if atomic.CompareAndSwapInt32(&co.stopped, 0, 1) {
+		close(co.stop)
+	}
+	select {
+	case <-co.done:
+	case <-ctx.Done():
+		err = errors.WithStack(ctx.Err())
+	}
+	return
+}
+
+// loop run on a new channel until stopped, reconnect if channel closed
+func (co *Consumer) loop(run func(ch *amqp.Channel) error) {
+	defer close(co.done)
+	rb := co.qu.ex.rb
+	for {
+		ch, err := rb.channel(rb.ops.ctx, co.stop, false)
+		if err != nil {
+			// stopped or rabbit closed
+			return
+		}
+		err = run(ch)
+		// unacked deliveries will be requeued by broker after channel closed
+		_ = ch.Close()
+		if err == nil {
+			return
+		}
+		log.WithContext(rb.ops.ctx).WithError(err).Warn("failed to consume queue: %s, retry...", co.q)
+		select {
+		case <-co.stop:
+			return
+		case <-time.After(time.Duration(rb.ops.healthCheckInterval) * time.Millisecond):
+		}
+	}
+}
+
+// consume receive deliveries until stopped(return nil) or channel closed
+func (co *Consumer) consume(ch *amqp.Channel, handler deliveryHandler) (err error) {
+	if co.ops.qosPrefetchCount > 0 {
+		err = ch.Qos(co.ops.qosPrefetchCount, 0, false)
+		if err != nil {
 			return
 		}
-		if ok {
-			e := a.Ack(tag, false)
+	}
+	ds, err := ch.Consume(
+		co.q,
+		co.tag,
+		co.ops.autoAck,
+		co.ops.exclusive,
+		false,
+		co.ops.noWait,
+		co.ops.args,
+	)
+	if err != nil {
+		return
+	}
+	for {
+		select {
+		case <-co.stop:
+			_ = ch.Cancel(co.tag, false)
+			return
+		case d, ok := <-ds:
+			if !ok {
+				err = errors.Errorf("delivery channel closed")
+				return
+			}
+			if atomic.LoadInt32(&co.stopped) == 1 {
+				if !co.ops.autoAck {
+					_ = d.Nack(false, true)
+				}
+				_ = ch.Cancel(co.tag, false)
+				return
+			}
+			co.deliver(co.qu.ex.rb.ops.ctx, d, handler)
+		}
+	}
+}
+
+func (co *Consumer) deliver(ctx context.Context, d amqp.Delivery, handler deliveryHandler) {
+	act := co.handle(ctx, d, handler)
+	if co.ops.autoAck {
+		return
+	}
+	if act == actionAck {
+		e := d.Ack(false)
+		if e != nil {
+			log.WithContext(ctx).WithError(e).Error("consume ack failed")
+		}
+		return
+	}
+	if act == actionReject {
+		e := d.Nack(false, false)
+		if e != nil {
+			log.WithContext(ctx).WithError(e).Error("consume reject failed")
+		}
+		return
+	}
+	requeue := co.ops.nackRequeue
+	if co.retryable() {
+		e := co.retry(ctx, d)
+		if e == nil {
+			e = d.Ack(false)
 			if e != nil {
-				log.WithError(e).Error("consume ack failed")
+				log.WithContext(ctx).WithError(e).Error("consume ack retried delivery failed")
 			}
 			return
 		}
-		e := a.Nack(tag, false, co.ops.nackRequeue)
-		if e != nil {
-			log.WithError(e).Error("consume nack failed")
+		if errors.Is(e, errRetryExceeded) {
+			// requeue it means retry forever
+			requeue = false
+		} else {
+			log.WithContext(ctx).WithError(e).Warn("consume retry failed, nack it")
 		}
-	})
+	}
+	e := d.Nack(false, requeue)
+	if e != nil {
+		log.WithContext(ctx).WithError(e).Error("consume nack failed")
+	}
+}
+
+// handle call handler with trace context extracted from headers and deadline
+func (co *Consumer) handle(ctx context.Context, d amqp.Delivery, handler deliveryHandler) (act action) {
+	ctx = co.qu.ex.rb.extractHeaders(ctx, d.Headers)
+	tr := otel.Tracer("rabbit")
+	ctx, span := tr.Start(ctx, "Consume", trace.WithSpanKind(trace.SpanKindConsumer))
+	defer func() {
+		if act != actionAck {
+			span.SetStatus(codes.Error, "handler nack")
+		}
+		span.End()
+	}()
+	span.SetAttributes(
+		attribute.String("exchange", d.Exchange),
+		attribute.String("route.key", d.RoutingKey),
+		attribute.String("queue", co.q),
+	)
+	if co.ops.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(co.ops.timeout)*time.Millisecond)
+		defer cancel()
+	}
+	act = handler(ctx, co.q, d)
 	return
 }
 
@@ -66,8 +237,8 @@ func (qu *Queue) ConsumeOne(size int, handler func(c context.Context, q string,
 		return
 	}
 
-	if atomic.LoadInt32(&qu.ex.rb.lost) == 1 {
-		err = errors.Errorf("connection maybe lost")
+	err = qu.ex.rb.Ping()
+	if err != nil {
 		return
 	}
 	co := qu.beforeConsume(options...)
@@ -81,53 +252,13 @@ func (qu *Queue) ConsumeOne(size int, handler func(c context.Context, q string,
 		ctx = co.ops.oneCtx
 	}
 	for i, d := range ds {
-		a := d.Acknowledger
-		tag := d.DeliveryTag
-		ctx = context.WithValue(ctx, "index", i)
-		ok := handler(ctx, co.q, d)
-		if co.ops.autoAck {
-			return
-		}
-		if ok {
-			e := a.Ack(tag, false)
-			if e != nil {
-				log.WithContext(ctx).WithError(e).Error("consume one ack failed")
-			}
-			return
-		}
-		// get retry count
-		var retryCount int32
-		if v, o := d.Headers["x-retry-count"].(int32); o {
-			retryCount = v + 1
-		} else {
-			retryCount = 1
-		}
-		// need to retry, requeue with set custom header
-		if co.ops.nackRetry {
-			if retryCount < co.ops.nackMaxRetryCount {
-				d.Headers["x-retry-count"] = retryCount
-				err = qu.ex.PublishByte(
-					d.Body,
-					WithPublishHeaders(d.Headers),
-					WithPublishRouteKey(d.RoutingKey),
-				)
-				if err != nil {
-					log.WithContext(ctx).WithError(err).Error("consume one republish failed")
-				}
-			} else {
-				log.WithContext(ctx).Warn("maximum retry %d exceeded, discard data", co.ops.nackMaxRetryCount)
-			}
-		}
-		e := a.Nack(tag, false, co.ops.nackRequeue)
-		if e != nil {
-			log.WithContext(ctx).WithError(e).Error("consume one nack failed")
-		}
+		co.deliver(context.WithValue(ctx, "index", i), d, toDeliveryHandler(handler))
 	}
 	return
 }
 
-func (qu *Queue) beforeConsume(options ...func(*ConsumeOptions)) *Consume {
-	var co Consume
+func (qu *Queue) beforeConsume(options ...func(*ConsumeOptions)) *Consumer {
+	var co Consumer
 	if qu.Error != nil {
 		co.Error = qu.Error
 		return &co
@@ -137,22 +268,14 @@ func (qu *Queue) beforeConsume(options ...func(*ConsumeOptions)) *Consume {
 		f(ops)
 	}
 	co.ops = *ops
+	co.qu = qu
 	co.q = qu.ops.name
-	co.consumer = tcr.NewConsumerFromConfig(
-		&tcr.ConsumerConfig{
-			Enabled:              true,
-			QueueName:            qu.ops.name,
-			ConsumerName:         co.ops.consumer,
-			AutoAck:              co.ops.autoAck,
-			Exclusive:            co.ops.exclusive,
-			NoWait:               co.ops.noWait,
-			Args:                 co.ops.args,
-			QosCountOverride:     co.ops.qosPrefetchCount,
-			SleepOnIdleInterval:  uint32(qu.ex.rb.ops.healthCheckInterval),
-			SleepOnErrorInterval: uint32(qu.ex.rb.ops.healthCheckInterval),
-		},
-		qu.ex.rb.pool,
-	)
+	co.tag = ops.consumer
+	if co.tag == "" {
+		co.tag = strings.Join([]string{qu.ex.rb.poolConfig.ApplicationName, uuid.NewString()[:8]}, "-")
+	}
+	co.stop = make(chan struct{})
+	co.done = make(chan struct{})
 	return &co
 }
 
@@ -6,6 +6,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/go-cinch/common/rabbit/rabbittest"
 	"github.com/streadway/amqp"
 )
 
@@ -26,13 +27,19 @@ func TestQueue_Consume(t *testing.T) {
 		panic(qu.Error)
 	}
 
-	err := qu.Consume(
+	co, err := qu.Consume(
 		handler,
 		WithConsumeAutoRequestID(true),
+		WithConsumeTimeout(3000),
 	)
 	if err != nil {
 		t.Log(err)
+		return
 	}
+	time.Sleep(10 * time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	t.Log(co.Stop(ctx))
 }
 
 func handler(ctx context.Context, q string, delivery amqp.Delivery) bool {
@@ -62,3 +69,57 @@ func TestQueue_ConsumeOne(t *testing.T) {
 		t.Log(time.Now(), err)
 	}
 }
+
+func TestConsumer_Stop_reconnecting(t *testing.T) {
+	s, err := rabbittest.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rb := New(s.URI(), WithTimeout(1))
+	if rb.Error != nil {
+		t.Fatal(rb.Error)
+	}
+	defer rb.Close()
+	qu := rb.Exchange(WithExchangeName("ex1")).Queue(WithQueueName("q1"), WithQueueRouteKeys("rt1"))
+	if qu.Error != nil {
+		t.Fatal(qu.Error)
+	}
+	co, err := qu.Consume(handler)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// broker is down, consumer keeps reconnecting
+	s.Close()
+	time.Sleep(300 * time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	if err = co.Stop(ctx); err != nil {
+		t.Errorf("expect stopped while reconnecting, got %v", err)
+	}
+}
+
+func TestConsumer_loop_closed(t *testing.T) {
+	s, err := rabbittest.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	rb := New(s.URI())
+	if rb.Error != nil {
+		t.Fatal(rb.Error)
+	}
+	qu := rb.Exchange(WithExchangeName("ex1")).Queue(WithQueueName("q1"), WithQueueRouteKeys("rt1"))
+	if qu.Error != nil {
+		t.Fatal(qu.Error)
+	}
+	co, err := qu.Consume(handler)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rb.Close()
+	select {
+	case <-co.done:
+	case <-time.After(3 * time.Second):
+		t.Error("expect consumer loop exited after rabbit closed")
+	}
+}
@@ -0,0 +1,170 @@
+package rabbit
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/go-cinch/common/log"
+	"github.com/pkg/errors"
+)
+
+// State connection state of rabbit
+type State int32
+
+const (
+	StateConnecting State = iota
+	StateConnected
+	StateLost
+	StateClosed
+)
+
+func (s State) String() string {
+	switch s {
+	case StateConnecting:
+		return "connecting"
+	case StateConnected:
+		return "connected"
+	case StateLost:
+		return "lost"
+	case StateClosed:
+		return "closed"
+	}
+	return "unknown"
+}
+
+// Health snapshot of health check
+type Health struct {
+	State State
+	// LastError last connect error, it's kept after reconnected
+	LastError     error
+	LastConnected time.Time
+	LastChecked   time.Time
+}
+
+func (h Health) MarshalJSON() ([]byte, error) {
+	var lastErr string
+	if h.LastError != nil {
+		lastErr = h.LastError.Error()
+	}
+	return json.Marshal(struct {
+		State         string    `json:"state"`
+		LastError     string    `json:"lastError,omitempty"`
+		LastConnected time.Time `json:"lastConnected"`
+		LastChecked   time.Time `json:"lastChecked"`
+	}{
+		State:         h.State.String(),
+		LastError:     lastErr,
+		LastConnected: h.LastConnected,
+		LastChecked:   h.LastChecked,
+	})
+}
+
+// IsHealthy returns true if connection is connected, and the health snapshot
+func (rb *Rabbit) IsHealthy() (ok bool, h Health) {
+	if rb.Error != nil {
+		h.LastError = rb.Error
+		return
+	}
+	rb.healthLock.RLock()
+	h = rb.health
+	rb.healthLock.RUnlock()
+	ok = h.State == StateConnected
+	return
+}
+
+func (rb *Rabbit) Ping() (err error) {
+	ok, h := rb.IsHealthy()
+	if !ok {
+		err = errors.Errorf("connection maybe %s", h.State)
+	}
+	return
+}
+
+// HealthHandler readiness probe, responds 200 if healthy, otherwise 503, body is the health snapshot json,
+// it can be registered to kratos http server: srv.Handle("/ready", rb.HealthHandler())
+func (rb *Rabbit) HealthHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		ok, h := rb.IsHealthy()
+		w.Header().Set("Content-Type", "application/json")
+		if ok {
+			w.WriteHeader(http.StatusOK)
+		} else {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		_ = json.NewEncoder(w).Encode(h)
+	})
+}
+
+// Close stop health check and shutdown all connections
+func (rb *Rabbit) Close() {
+	if rb.stop == nil {
+		return
+	}
+	rb.once.Do(func() {
+		close(rb.stop)
+		<-rb.done
+		rb.acquireLock.Lock()
+		rb.closed = true
+		rb.acquireLock.Unlock()
+		rb.acquiring.Wait()
+		rb.pool.Shutdown()
+		rb.channelPool.Shutdown()
+		rb.healthPool.Shutdown()
+		rb.setState(StateClosed, nil)
+	})
+}
+
+func (rb *Rabbit) healthCheck() {
+	defer close(rb.done)
+	for {
+		select {
+		case <-rb.stop:
+			return
+		case <-time.After(time.Duration(rb.ops.healthCheckInterval) * time.Millisecond):
+		}
+		rb.check()
+	}
+}
+
+// check connect(reconnect if connection closed) and update state
+func (rb *Rabbit) check() {
+	var err error
+	ok := rb.healthHost.ConnectWithErrorHandler(func(e error) {
+		err = e
+	})
+	if ok {
+		rb.setState(StateConnected, nil)
+		return
+	}
+	if err == nil {
+		err = errors.Errorf("connect failed")
+	}
+	rb.setState(StateLost, err)
+}
+
+func (rb *Rabbit) setState(to State, err error) {
+	now := time.Now()
+	rb.healthLock.Lock()
+	from := rb.health.State
+	rb.health.State = to
+	rb.health.LastChecked = now
+	if to == StateConnected {
+		rb.health.LastConnected = now
+	}
+	if err != nil {
+		rb.health.LastError = err
+	}
+	rb.healthLock.Unlock()
+	if from == to {
+		return
+	}
+	if to == StateLost {
+		log.WithContext(rb.ops.ctx).WithError(err).Warn("rabbit connection %s -> %s", from, to)
+	} else {
+		log.WithContext(rb.ops.ctx).Info("rabbit connection %s -> %s", from, to)
+	}
+	for _, f := range rb.ops.onStateChange {
+		f(from, to, err)
+	}
+}
@@ -0,0 +1,44 @@
+package rabbit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestRabbit_setState(t *testing.T) {
+	var changes []State
+	rb := &Rabbit{ops: *getOptionsOrSetDefault(nil)}
+	WithOnStateChange(func(from, to State, err error) {
+		changes = append(changes, to)
+	})(&rb.ops)
+
+	rb.setState(StateConnected, nil)
+	rb.setState(StateConnected, nil)
+	if ok, _ := rb.IsHealthy(); !ok {
+		t.Errorf("expect healthy")
+		return
+	}
+	rb.setState(StateLost, errors.New("dial failed"))
+	ok, h := rb.IsHealthy()
+	if ok || h.LastError == nil || h.LastConnected.IsZero() {
+		t.Errorf("expect unhealthy with last error and last connected time, got %+v", h)
+		return
+	}
+	if err := rb.Ping(); err == nil {
+		t.Errorf("expect ping failed")
+		return
+	}
+	if len(changes) != 2 || changes[0] != StateConnected || changes[1] != StateLost {
+		t.Errorf("expect connected and lost changes, got %v", changes)
+		return
+	}
+
+	w := httptest.NewRecorder()
+	rb.HealthHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ready", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expect 503, got %d", w.Code)
+	}
+}
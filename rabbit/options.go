@@ -4,8 +4,10 @@ import (
 	"context"
 	"reflect"
 
+	"github.com/go-kratos/kratos/v2/middleware"
 	"github.com/samber/lo"
 	"github.com/streadway/amqp"
+	"go.opentelemetry.io/otel/propagation"
 )
 
 type Options struct {
@@ -16,6 +18,8 @@ type Options struct {
 	maxConnection       int
 	maxChannel          int
 	healthCheckInterval int
+	propagator          propagation.TextMapPropagator
+	onStateChange       []func(from, to State, err error)
 }
 
 func WithCtx(ctx context.Context) func(*Options) {
@@ -72,6 +76,23 @@ func WithHealthCheckInterval(milli int) func(*Options) {
 	}
 }
 
+// WithPropagator set trace propagator used to inject/extract amqp headers, default w3c trace context and baggage
+func WithPropagator(propagator propagation.TextMapPropagator) func(*Options) {
+	return func(options *Options) {
+		if propagator != nil {
+			getOptionsOrSetDefault(options).propagator = propagator
+		}
+	}
+}
+
+// WithOnStateChange callbacks when connection state changed, such as connected -> lost, err is the last connect error
+func WithOnStateChange(fun ...func(from, to State, err error)) func(*Options) {
+	return func(options *Options) {
+		ops := getOptionsOrSetDefault(options)
+		ops.onStateChange = append(ops.onStateChange, fun...)
+	}
+}
+
 func getOptionsOrSetDefault(options *Options) *Options {
 	if options == nil {
 		return &Options{
@@ -81,6 +102,10 @@ func getOptionsOrSetDefault(options *Options) *Options {
 			maxConnection:       10,
 			maxChannel:          50,
 			healthCheckInterval: 100,
+			propagator: propagation.NewCompositeTextMapPropagator(
+				propagation.TraceContext{},
+				propagation.Baggage{},
+			),
 		}
 	}
 	return options
@@ -178,6 +203,7 @@ type QueueOptions struct {
 	deadLetterKey  string
 	messageTTL     int32
 	namePrefix     string
+	retryDelays    []int32
 }
 
 func WithQueueName(name string) func(*QueueOptions) {
@@ -258,6 +284,23 @@ func WithQueueMessageTTL(ttl int32) func(*QueueOptions) {
 	}
 }
 
+// WithQueueRetryDelays delay(milli second) of each retry, used by QueueWithRetry and consumers
+func WithQueueRetryDelays(delays ...int32) func(*QueueOptions) {
+	return func(options *QueueOptions) {
+		ops := getQueueOptionsOrSetDefault(options)
+		for _, item := range delays {
+			if item > 0 {
+				ops.retryDelays = append(ops.retryDelays, item)
+			}
+		}
+	}
+}
+
+// WithQueueRetryBackoff exponential retry delays, such as 1000, 2.0, 5 => 1s, 2s, 4s, 8s, 16s
+func WithQueueRetryBackoff(initial int32, factor float64, count int) func(*QueueOptions) {
+	return WithQueueRetryDelays(RetryBackoff(initial, factor, count)...)
+}
+
 func getQueueOptionsOrSetDefault(options *QueueOptions) *QueueOptions {
 	if options == nil {
 		return &QueueOptions{
@@ -284,6 +327,10 @@ type PublishOptions struct {
 	expiration           string
 	deadLetter           bool
 	deadLetterFirstQueue string
+	confirm              bool
+	messageID            string
+	msgType              string
+	origin               *amqp.Delivery
 }
 
 func WithPublishCtx(ctx context.Context) func(*PublishOptions) {
@@ -363,6 +410,41 @@ func WithPublishDeadLetterFirstQueue(q string) func(*PublishOptions) {
 	}
 }
 
+// WithPublishMandatory broker returns the msg when it can't be routed to any queue
+func WithPublishMandatory(flag bool) func(*PublishOptions) {
+	return func(options *PublishOptions) {
+		getPublishOptionsOrSetDefault(options).mandatory = flag
+	}
+}
+
+// WithPublishConfirm wait broker ack/nack(and return if mandatory) until timeout
+func WithPublishConfirm(flag bool) func(*PublishOptions) {
+	return func(options *PublishOptions) {
+		getPublishOptionsOrSetDefault(options).confirm = flag
+	}
+}
+
+// WithPublishMessageID msg id, default random uuid, non uuid id is kept by publishing on a new channel
+func WithPublishMessageID(id string) func(*PublishOptions) {
+	return func(options *PublishOptions) {
+		getPublishOptionsOrSetDefault(options).messageID = id
+	}
+}
+
+// WithPublishType msg type name
+func WithPublishType(t string) func(*PublishOptions) {
+	return func(options *PublishOptions) {
+		getPublishOptionsOrSetDefault(options).msgType = t
+	}
+}
+
+// withPublishOrigin keep properties of the republished delivery, used by retry
+func withPublishOrigin(d amqp.Delivery) func(*PublishOptions) {
+	return func(options *PublishOptions) {
+		getPublishOptionsOrSetDefault(options).origin = &d
+	}
+}
+
 func getPublishOptionsOrSetDefault(options *PublishOptions) *PublishOptions {
 	if options == nil {
 		return &PublishOptions{
@@ -390,6 +472,7 @@ type ConsumeOptions struct {
 	nackMaxRetryCount int32
 	autoRequestID     bool
 	oneCtx            context.Context
+	timeout           int
 }
 
 func WithConsumeQosPrefetchCount(prefetchCount int) func(*ConsumeOptions) {
@@ -460,6 +543,15 @@ func WithConsumeOneContext(ctx context.Context) func(*ConsumeOptions) {
 	}
 }
 
+// WithConsumeTimeout handler deadline of each delivery, default 0 means no deadline
+func WithConsumeTimeout(milli int) func(*ConsumeOptions) {
+	return func(options *ConsumeOptions) {
+		if milli > 0 {
+			getConsumeOptionsOrSetDefault(options).timeout = milli
+		}
+	}
+}
+
 func getConsumeOptionsOrSetDefault(options *ConsumeOptions) *ConsumeOptions {
 	if options == nil {
 		return &ConsumeOptions{
@@ -470,6 +562,32 @@ func getConsumeOptionsOrSetDefault(options *ConsumeOptions) *ConsumeOptions {
 	return options
 }
 
+type ServerOptions struct {
+	middlewares []middleware.Middleware
+	endpoint    string
+}
+
+// WithServerMiddleware kratos middlewares applied to every delivery, such as logging/tenant/i18n
+func WithServerMiddleware(m ...middleware.Middleware) func(*ServerOptions) {
+	return func(options *ServerOptions) {
+		getServerOptionsOrSetDefault(options).middlewares = m
+	}
+}
+
+// WithServerEndpoint endpoint of transport, default is broker address without credentials
+func WithServerEndpoint(endpoint string) func(*ServerOptions) {
+	return func(options *ServerOptions) {
+		getServerOptionsOrSetDefault(options).endpoint = endpoint
+	}
+}
+
+func getServerOptionsOrSetDefault(options *ServerOptions) *ServerOptions {
+	if options == nil {
+		return &ServerOptions{}
+	}
+	return options
+}
+
 func interfaceIsNil(i interface{}) bool {
 	v := reflect.ValueOf(i)
 	if v.Kind() == reflect.Ptr {
@@ -0,0 +1,56 @@
+module github.com/go-cinch/common/rabbit/outbox
+
+go 1.25
+
+replace (
+	github.com/go-cinch/common/log => ../../log
+	github.com/go-cinch/common/migrate/v2 => ../../migrate
+	github.com/go-cinch/common/plugins/kratos/encoding/yml => ../../plugins/kratos/encoding/yml
+	github.com/go-cinch/common/rabbit => ../
+)
+
+require (
+	github.com/go-cinch/common/log v1.2.0
+	github.com/go-cinch/common/migrate/v2 v2.0.2
+	github.com/go-cinch/common/rabbit v1.0.0
+	github.com/pkg/errors v0.9.1
+	github.com/streadway/amqp v1.1.0
+	go.opentelemetry.io/otel v1.34.0
+	gorm.io/driver/mysql v1.6.0
+	gorm.io/driver/sqlite v1.6.0
+	gorm.io/gorm v1.31.1
+)
+
+require (
+	dario.cat/mergo v1.0.0 // indirect
+	filippo.io/edwards25519 v1.1.0 // indirect
+	github.com/Workiva/go-datastructures v1.1.0 // indirect
+	github.com/go-cinch/common/plugins/kratos/encoding/yml v1.0.0 // indirect
+	github.com/go-gorp/gorp/v3 v3.1.0 // indirect
+	github.com/go-kratos/kratos/v2 v2.8.3 // indirect
+	github.com/go-logr/logr v1.4.2 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+	github.com/go-playground/form/v4 v4.2.0 // indirect
+	github.com/go-sql-driver/mysql v1.8.1 // indirect
+	github.com/google/uuid v1.6.0 // indirect
+	github.com/houseofcat/turbocookedrabbit/v2 v2.3.0 // indirect
+	github.com/jinzhu/inflection v1.0.0 // indirect
+	github.com/jinzhu/now v1.1.5 // indirect
+	github.com/json-iterator/go v1.1.12 // indirect
+	github.com/klauspost/compress v1.16.6 // indirect
+	github.com/lib/pq v1.10.9 // indirect
+	github.com/mattn/go-sqlite3 v1.14.22 // indirect
+	github.com/modern-go/concurrent v0.0.0-20180306012644-bacd9c7ef1dd // indirect
+	github.com/modern-go/reflect2 v1.0.2 // indirect
+	github.com/rubenv/sql-migrate v1.8.1 // indirect
+	github.com/samber/lo v1.49.1 // indirect
+	github.com/sirupsen/logrus v1.9.3 // indirect
+	go.opentelemetry.io/auto/sdk v1.1.0 // indirect
+	go.opentelemetry.io/otel/metric v1.34.0 // indirect
+	go.opentelemetry.io/otel/trace v1.34.0 // indirect
+	golang.org/x/crypto v0.45.0 // indirect
+	golang.org/x/sys v0.38.0 // indirect
+	golang.org/x/text v0.31.0 // indirect
+	google.golang.org/protobuf v1.33.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
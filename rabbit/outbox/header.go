@@ -0,0 +1,189 @@
+package outbox
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/streadway/amqp"
+)
+
+// header amqp header value with its type, plain json loses types(int32 becomes float64, []byte becomes base64)
+type header struct {
+	Type  string          `json:"t"`
+	Value json.RawMessage `json:"v,omitempty"`
+}
+
+// marshalHeaders encode headers as json with types, value types not supported by amqp are rejected
+func marshalHeaders(headers amqp.Table) (s string, err error) {
+	m, err := encodeTable(headers)
+	if err != nil {
+		return
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		err = errors.WithStack(err)
+		return
+	}
+	s = string(b)
+	return
+}
+
+func unmarshalHeaders(s string) (headers amqp.Table, err error) {
+	headers = make(amqp.Table)
+	if s == "" {
+		return
+	}
+	var m map[string]header
+	err = json.Unmarshal([]byte(s), &m)
+	if err != nil {
+		err = errors.WithStack(err)
+		return
+	}
+	headers, err = decodeTable(m)
+	return
+}
+
+func encodeTable(t amqp.Table) (m map[string]header, err error) {
+	m = make(map[string]header, len(t))
+	for k, v := range t {
+		m[k], err = encodeHeader(v)
+		if err != nil {
+			err = errors.WithMessagef(err, "header %s", k)
+			return
+		}
+	}
+	return
+}
+
+func decodeTable(m map[string]header) (t amqp.Table, err error) {
+	t = make(amqp.Table, len(m))
+	for k, v := range m {
+		t[k], err = decodeHeader(v)
+		if err != nil {
+			err = errors.WithMessagef(err, "header %s", k)
+			return
+		}
+	}
+	return
+}
+
+func encodeHeader(v interface{}) (h header, err error) {
+	var value interface{}
+	switch item := v.(type) {
+	case nil:
+		h.Type = "nil"
+		return
+	case bool:
+		h.Type, value = "bool", item
+	case byte:
+		h.Type, value = "byte", item
+	case int:
+		h.Type, value = "int", item
+	case int16:
+		h.Type, value = "int16", item
+	case int32:
+		h.Type, value = "int32", item
+	case int64:
+		h.Type, value = "int64", item
+	case float32:
+		h.Type, value = "float32", item
+	case float64:
+		h.Type, value = "float64", item
+	case string:
+		h.Type, value = "string", item
+	case []byte:
+		h.Type, value = "bytes", item
+	case amqp.Decimal:
+		h.Type, value = "decimal", item
+	case time.Time:
+		h.Type, value = "time", item
+	case []interface{}:
+		arr := make([]header, len(item))
+		for i, elem := range item {
+			arr[i], err = encodeHeader(elem)
+			if err != nil {
+				return
+			}
+		}
+		h.Type, value = "array", arr
+	case amqp.Table:
+		var m map[string]header
+		m, err = encodeTable(item)
+		if err != nil {
+			return
+		}
+		h.Type, value = "table", m
+	default:
+		err = errors.Errorf("value %T not supported", v)
+		return
+	}
+	h.Value, err = json.Marshal(value)
+	if err != nil {
+		err = errors.WithStack(err)
+	}
+	return
+}
+
+func decodeHeader(h header) (v interface{}, err error) {
+	switch h.Type {
+	case "nil":
+		return
+	case "bool":
+		v, err = decodeValue[bool](h.Value)
+	case "byte":
+		v, err = decodeValue[byte](h.Value)
+	case "int":
+		v, err = decodeValue[int](h.Value)
+	case "int16":
+		v, err = decodeValue[int16](h.Value)
+	case "int32":
+		v, err = decodeValue[int32](h.Value)
+	case "int64":
+		v, err = decodeValue[int64](h.Value)
+	case "float32":
+		v, err = decodeValue[float32](h.Value)
+	case "float64":
+		v, err = decodeValue[float64](h.Value)
+	case "string":
+		v, err = decodeValue[string](h.Value)
+	case "bytes":
+		v, err = decodeValue[[]byte](h.Value)
+	case "decimal":
+		v, err = decodeValue[amqp.Decimal](h.Value)
+	case "time":
+		v, err = decodeValue[time.Time](h.Value)
+	case "array":
+		var arr []header
+		arr, err = decodeValue[[]header](h.Value)
+		if err != nil {
+			return
+		}
+		items := make([]interface{}, len(arr))
+		for i, elem := range arr {
+			items[i], err = decodeHeader(elem)
+			if err != nil {
+				return
+			}
+		}
+		v = items
+	case "table":
+		var m map[string]header
+		m, err = decodeValue[map[string]header](h.Value)
+		if err != nil {
+			return
+		}
+		v, err = decodeTable(m)
+	default:
+		err = errors.Errorf("type %s not supported", h.Type)
+	}
+	return
+}
+
+func decodeValue[T any](b json.RawMessage) (v T, err error) {
+	err = json.Unmarshal(b, &v)
+	if err != nil {
+		err = errors.WithStack(err)
+	}
+	return
+}
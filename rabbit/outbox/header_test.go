@@ -0,0 +1,44 @@
+package outbox
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+func TestMarshalHeaders(t *testing.T) {
+	headers := amqp.Table{
+		"nil":     nil,
+		"bool":    true,
+		"byte":    byte(1),
+		"int16":   int16(2),
+		"int32":   int32(3),
+		"int64":   int64(4),
+		"float32": float32(1.5),
+		"float64": 2.5,
+		"string":  "s",
+		"bytes":   []byte("b"),
+		"decimal": amqp.Decimal{Scale: 2, Value: 314},
+		"time":    time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC),
+		"array":   []interface{}{int32(1), "a"},
+		"table":   amqp.Table{"n": int64(5)},
+	}
+	s, err := marshalHeaders(headers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rp, err := unmarshalHeaders(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(rp, headers) {
+		t.Errorf("expect %v, got %v", headers, rp)
+	}
+
+	_, err = marshalHeaders(amqp.Table{"table": amqp.Table{"v": struct{}{}}})
+	if err == nil {
+		t.Error("expect unsupported value rejected")
+	}
+}
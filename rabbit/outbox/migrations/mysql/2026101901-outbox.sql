@@ -0,0 +1,19 @@
+-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+CREATE TABLE `rabbit_outbox`
+(
+	`id`           BIGINT UNSIGNED AUTO_INCREMENT COMMENT 'auto increment id' PRIMARY KEY,
+	`exchange`     VARCHAR(191) NOT NULL COMMENT 'exchange name',
+	`route_key`    VARCHAR(191) NOT NULL COMMENT 'route key',
+	`content_type` VARCHAR(100) NOT NULL DEFAULT '' COMMENT 'content type',
+	`headers`      TEXT NULL COMMENT 'amqp headers json with value types',
+	`body`         LONGBLOB NOT NULL COMMENT 'msg body',
+	`status`       TINYINT NOT NULL DEFAULT 0 COMMENT 'status: 0 pending, 1 sent, 2 failed',
+	`retry_count`  INT NOT NULL DEFAULT 0 COMMENT 'publish retry count',
+	`last_error`   VARCHAR(500) NOT NULL DEFAULT '' COMMENT 'last publish error',
+	`created_at`   DATETIME(3) NULL COMMENT 'create time',
+	`sent_at`      DATETIME(3) NULL COMMENT 'sent time',
+	KEY `idx_status_id` (`status`, `id`)
+) ENGINE = InnoDB
+	DEFAULT CHARSET = utf8mb4
+	COLLATE = utf8mb4_general_ci;
@@ -0,0 +1,18 @@
+-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+CREATE TABLE rabbit_outbox
+(
+	id           BIGSERIAL PRIMARY KEY,
+	exchange     VARCHAR(191) NOT NULL,
+	route_key    VARCHAR(191) NOT NULL,
+	content_type VARCHAR(100) NOT NULL DEFAULT '',
+	headers      TEXT NULL,
+	body         BYTEA NOT NULL,
+	status       SMALLINT NOT NULL DEFAULT 0,
+	retry_count  INT NOT NULL DEFAULT 0,
+	last_error   VARCHAR(500) NOT NULL DEFAULT '',
+	created_at   TIMESTAMP(3) NULL,
+	sent_at      TIMESTAMP(3) NULL
+);
+CREATE INDEX idx_rabbit_outbox_status_id ON rabbit_outbox (status, id);
+COMMENT ON TABLE rabbit_outbox IS 'rabbit transactional outbox';
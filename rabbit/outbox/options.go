@@ -0,0 +1,110 @@
+package outbox
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/go-cinch/common/rabbit"
+	"gorm.io/gorm"
+)
+
+type Options struct {
+	ctx       context.Context
+	db        *gorm.DB
+	rabbit    *rabbit.Rabbit
+	batchSize int
+	interval  int
+	maxRetry  int
+	timeout   int
+	mandatory bool
+}
+
+func WithCtx(ctx context.Context) func(*Options) {
+	return func(options *Options) {
+		if !interfaceIsNil(ctx) {
+			getOptionsOrSetDefault(options).ctx = ctx
+		}
+	}
+}
+
+// WithDB db which has outbox table
+func WithDB(db *gorm.DB) func(*Options) {
+	return func(options *Options) {
+		if db != nil {
+			getOptionsOrSetDefault(options).db = db
+		}
+	}
+}
+
+// WithRabbit rabbit instance used to publish events
+func WithRabbit(rb *rabbit.Rabbit) func(*Options) {
+	return func(options *Options) {
+		if rb != nil {
+			getOptionsOrSetDefault(options).rabbit = rb
+		}
+	}
+}
+
+// WithBatchSize max events locked by relay each round, default 100
+func WithBatchSize(size int) func(*Options) {
+	return func(options *Options) {
+		if size > 0 {
+			getOptionsOrSetDefault(options).batchSize = size
+		}
+	}
+}
+
+// WithInterval relay poll interval, default 1000 milli second
+func WithInterval(milli int) func(*Options) {
+	return func(options *Options) {
+		if milli > 0 {
+			getOptionsOrSetDefault(options).interval = milli
+		}
+	}
+}
+
+// WithMaxRetry event will be marked as failed after max retry, default 10
+func WithMaxRetry(count int) func(*Options) {
+	return func(options *Options) {
+		if count > 0 {
+			getOptionsOrSetDefault(options).maxRetry = count
+		}
+	}
+}
+
+// WithTimeout publish confirm timeout, default 10000 milli second
+func WithTimeout(milli int) func(*Options) {
+	return func(options *Options) {
+		if milli > 0 {
+			getOptionsOrSetDefault(options).timeout = milli
+		}
+	}
+}
+
+// WithMandatory unroutable event will be treated as publish failed, default false
+func WithMandatory(flag bool) func(*Options) {
+	return func(options *Options) {
+		getOptionsOrSetDefault(options).mandatory = flag
+	}
+}
+
+func getOptionsOrSetDefault(options *Options) *Options {
+	if options == nil {
+		return &Options{
+			ctx:       context.Background(),
+			batchSize: 100,
+			interval:  1000,
+			maxRetry:  10,
+			timeout:   10000,
+		}
+	}
+	return options
+}
+
+func interfaceIsNil(i interface{}) bool {
+	v := reflect.ValueOf(i)
+	if v.Kind() == reflect.Ptr {
+		return v.IsNil()
+	}
+	return i == nil
+}
@@ -0,0 +1,339 @@
+package outbox
+
+import (
+	"context"
+	"embed"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-cinch/common/log"
+	migrate "github.com/go-cinch/common/migrate/v2"
+	"github.com/go-cinch/common/rabbit"
+	"github.com/pkg/errors"
+	"github.com/streadway/amqp"
+	"go.opentelemetry.io/otel/propagation"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+//go:embed migrations
+var sqlFs embed.FS
+
+const (
+	Table       = "rabbit_outbox"
+	ChangeTable = "rabbit_outbox_migrations"
+)
+
+const (
+	StatusPending int8 = iota
+	StatusSent
+	StatusFailed
+)
+
+// Message is the msg will be published after tx committed
+type Message struct {
+	Exchange    string
+	RouteKey    string
+	ContentType string
+	Headers     amqp.Table
+	Body        []byte
+}
+
+// Event is a row of outbox table
+type Event struct {
+	ID          uint64     `gorm:"column:id;primaryKey;autoIncrement"`
+	Exchange    string     `gorm:"column:exchange"`
+	RouteKey    string     `gorm:"column:route_key"`
+	ContentType string     `gorm:"column:content_type"`
+	Headers     string     `gorm:"column:headers"`
+	Body        []byte     `gorm:"column:body"`
+	Status      int8       `gorm:"column:status"`
+	RetryCount  int        `gorm:"column:retry_count"`
+	LastError   string     `gorm:"column:last_error"`
+	CreatedAt   time.Time  `gorm:"column:created_at"`
+	SentAt      *time.Time `gorm:"column:sent_at"`
+}
+
+func (Event) TableName() string {
+	return Table
+}
+
+// Migrate create outbox table, driver supports mysql and postgres
+func Migrate(driver, uri string, options ...func(*migrate.Options)) error {
+	ops := []func(*migrate.Options){
+		migrate.WithDriver(driver),
+		migrate.WithURI(uri),
+		migrate.WithFs(sqlFs),
+		migrate.WithFsRoot(strings.Join([]string{"migrations", driver}, "/")),
+		migrate.WithChangeTable(ChangeTable),
+	}
+	return migrate.Do(append(ops, options...)...)
+}
+
+// Add write msgs to outbox table, tx must be the transaction of business data.
+// header values must be amqp field types, they are saved with types so relay publishes them as is
+func Add(tx *gorm.DB, msgs ...Message) (err error) {
+	if tx == nil {
+		err = errors.Errorf("tx is nil")
+		return
+	}
+	if len(msgs) == 0 {
+		return
+	}
+	events := make([]Event, 0, len(msgs))
+	now := time.Now()
+	for _, item := range msgs {
+		if item.Exchange == "" || item.RouteKey == "" {
+			err = errors.Errorf("exchange or route key is empty")
+			return
+		}
+		if len(item.Body) == 0 {
+			err = errors.Errorf("msg is empty")
+			return
+		}
+		headers := make(amqp.Table, len(item.Headers))
+		for k, v := range item.Headers {
+			headers[k] = v
+		}
+		// keep trace context of tx, relay will publish it as is
+		carrier := make(propagation.MapCarrier)
+		propagation.TraceContext{}.Inject(tx.Statement.Context, carrier)
+		for k, v := range carrier {
+			headers[k] = v
+		}
+		var b string
+		b, err = marshalHeaders(headers)
+		if err != nil {
+			return
+		}
+		events = append(events, Event{
+			Exchange:    item.Exchange,
+			RouteKey:    item.RouteKey,
+			ContentType: item.ContentType,
+			Headers:     b,
+			Body:        item.Body,
+			Status:      StatusPending,
+			CreatedAt:   now,
+		})
+	}
+	err = tx.Create(&events).Error
+	if err != nil {
+		err = errors.WithStack(err)
+	}
+	return
+}
+
+// Outbox relay pending events to rabbit
+type Outbox struct {
+	ops   Options
+	exs   sync.Map
+	stop  chan struct{}
+	done  chan struct{}
+	once  sync.Once
+	Error error
+}
+
+func New(options ...func(*Options)) (ob *Outbox) {
+	ops := getOptionsOrSetDefault(nil)
+	for _, f := range options {
+		f(ops)
+	}
+	ob = &Outbox{
+		ops:  *ops,
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	if ops.db == nil {
+		ob.Error = errors.Errorf("db is nil")
+		return
+	}
+	if ops.rabbit == nil {
+		ob.Error = errors.Errorf("rabbit is nil")
+		return
+	}
+	if ops.rabbit.Error != nil {
+		ob.Error = ops.rabbit.Error
+	}
+	return
+}
+
+// Start relay in background until Stop
+func (ob *Outbox) Start() (err error) {
+	if ob.Error != nil {
+		err = errors.WithStack(ob.Error)
+		return
+	}
+	go ob.loop()
+	return
+}
+
+// Stop relay and wait current round finished or ctx done
+func (ob *Outbox) Stop(ctx context.Context) (err error) {
+	ob.once.Do(func() {
+		close(ob.stop)
+	})
+	select {
+	case <-ob.done:
+	case <-ctx.Done():
+		err = errors.WithStack(ctx.Err())
+	}
+	return
+}
+
+func (ob *Outbox) loop() {
+	defer close(ob.done)
+	ticker := time.NewTicker(time.Duration(ob.ops.interval) * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		count, err := ob.Relay(ob.ops.ctx)
+		if err != nil {
+			log.WithContext(ob.ops.ctx).WithError(err).Warn("outbox relay failed")
+		}
+		// a full batch sent, maybe more pending events
+		if err == nil && count == ob.ops.batchSize {
+			select {
+			case <-ob.stop:
+				return
+			default:
+				continue
+			}
+		}
+		select {
+		case <-ob.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Relay publish a batch of pending events with confirm, returns sent count.
+// each event is locked(skip rows locked by other relays), published and marked in its own transaction,
+// so a failed update only affects the event being relayed
+func (ob *Outbox) Relay(ctx context.Context) (count int, err error) {
+	if ob.Error != nil {
+		err = errors.WithStack(ob.Error)
+		return
+	}
+	var ids []uint64
+	err = ob.ops.db.
+		WithContext(ctx).
+		Model(&Event{}).
+		Where("status = ?", StatusPending).
+		Order("id").
+		Limit(ob.ops.batchSize).
+		Pluck("id", &ids).Error
+	if err != nil {
+		err = errors.WithStack(err)
+		return
+	}
+	for _, id := range ids {
+		var sent bool
+		sent, err = ob.relay(ctx, id)
+		if err != nil {
+			err = errors.WithStack(err)
+			return
+		}
+		if sent {
+			count++
+		}
+	}
+	return
+}
+
+// relay lock one pending event, publish it and update status
+func (ob *Outbox) relay(ctx context.Context, id uint64) (sent bool, err error) {
+	err = ob.ops.db.WithContext(ctx).Transaction(func(tx *gorm.DB) (err error) {
+		var events []Event
+		err = tx.
+			Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("id = ? AND status = ?", id, StatusPending).
+			Find(&events).Error
+		// relayed by others
+		if err != nil || len(events) == 0 {
+			return
+		}
+		item := events[0]
+		updates := make(map[string]interface{})
+		e := ob.publish(ctx, item)
+		if e == nil {
+			sent = true
+			updates["status"] = StatusSent
+			updates["sent_at"] = time.Now()
+		} else {
+			retry := item.RetryCount + 1
+			updates["retry_count"] = retry
+			updates["last_error"] = truncate(e.Error())
+			if retry >= ob.ops.maxRetry {
+				updates["status"] = StatusFailed
+			}
+			log.
+				WithContext(ctx).
+				WithError(e).
+				WithField("outbox.id", item.ID).
+				Warn("outbox publish failed, retry: %d", retry)
+		}
+		err = tx.Model(&Event{}).Where("id = ?", item.ID).Updates(updates).Error
+		return
+	})
+	if err != nil {
+		sent = false
+	}
+	return
+}
+
+func (ob *Outbox) publish(ctx context.Context, ev Event) (err error) {
+	ex := ob.exchange(ev.Exchange)
+	if ex.Error != nil {
+		err = ex.Error
+		return
+	}
+	headers, err := unmarshalHeaders(ev.Headers)
+	if err != nil {
+		return
+	}
+	options := []func(*rabbit.PublishOptions){
+		rabbit.WithPublishCtx(ctx),
+		rabbit.WithPublishRouteKey(ev.RouteKey),
+		// consumers can dedupe redelivered events by msg id
+		rabbit.WithPublishMessageID(strconv.FormatUint(ev.ID, 10)),
+		rabbit.WithPublishHeaders(headers),
+		rabbit.WithPublishTimeout(ob.ops.timeout),
+		rabbit.WithPublishMandatory(ob.ops.mandatory),
+		rabbit.WithPublishConfirm(true),
+	}
+	if ev.ContentType != "" {
+		options = append(options, rabbit.WithPublishContentType(ev.ContentType))
+	}
+	err = ex.PublishByte(ev.Body, options...)
+	return
+}
+
+// exchange get cached exchange without declare
+func (ob *Outbox) exchange(name string) *rabbit.Exchange {
+	if v, ok := ob.exs.Load(name); ok {
+		return v.(*rabbit.Exchange)
+	}
+	ex := ob.ops.rabbit.Exchange(
+		rabbit.WithExchangeName(name),
+		rabbit.WithExchangeDeclare(false),
+	)
+	if ex.Error == nil {
+		ob.exs.Store(name, ex)
+	}
+	return ex
+}
+
+// truncate s to 500 bytes on rune boundary
+func truncate(s string) string {
+	if len(s) <= 500 {
+		return s
+	}
+	i := 497
+	for i > 0 && !utf8.RuneStart(s[i]) {
+		i--
+	}
+	return s[:i] + "..."
+}
@@ -1,9 +1,10 @@
 package rabbit
 
 import (
-	"sync/atomic"
+	"context"
 	"time"
 
+	"github.com/go-cinch/common/log"
 	"github.com/google/uuid"
 	"github.com/houseofcat/turbocookedrabbit/v2/pkg/tcr"
 	"github.com/pkg/errors"
@@ -11,6 +12,12 @@ import (
 	"google.golang.org/protobuf/proto"
 )
 
+var (
+	ErrPublishNack     = errors.New("publish nack by broker")
+	ErrPublishReturned = errors.New("publish returned by broker, msg is unroutable")
+	ErrPublishTimeout  = errors.New("publish confirm timeout")
+)
+
 type Publish struct {
 	ops       PublishOptions
 	ex        *Exchange
@@ -71,6 +78,8 @@ func (ex *Exchange) beforePublish(options ...func(*PublishOptions)) *Publish {
 		pu.Error = errors.Errorf("route key is empty")
 		return &pu
 	}
+	// carry trace context to consumers
+	ops.headers = ex.rb.injectHeaders(ops.ctx, ops.headers)
 	if ops.deadLetter {
 		if ops.deadLetterFirstQueue == "" {
 			pu.Error = errors.Errorf("dead letter first queue is empty")
@@ -91,6 +100,18 @@ func (ex *Exchange) beforePublish(options ...func(*PublishOptions)) *Publish {
 		ContentType:  ops.contentType,
 		Headers:      ops.headers,
 		Expiration:   ops.expiration,
+		MessageId:    ops.messageID,
+		Type:         ops.msgType,
+	}
+	if d := ops.origin; d != nil {
+		if !d.Timestamp.IsZero() {
+			msg.Timestamp = d.Timestamp
+		}
+		msg.ContentEncoding = d.ContentEncoding
+		msg.CorrelationId = d.CorrelationId
+		msg.Priority = d.Priority
+		msg.ReplyTo = d.ReplyTo
+		msg.AppId = d.AppId
 	}
 	pu.msg = msg
 	pu.ex = ex
@@ -110,11 +131,21 @@ func (ex *Exchange) beforePublish(options ...func(*PublishOptions)) *Publish {
 }
 
 func (pu *Publish) publish() (err error) {
-	if atomic.LoadInt32(&pu.ex.rb.lost) == 1 {
-		err = errors.Errorf("connection maybe lost")
+	err = pu.ex.rb.Ping()
+	if err != nil {
 		return
 	}
+	// tcr publisher always uses an uuid letter id as msg id
+	_, e := uuid.Parse(pu.msg.MessageId)
+	custom := pu.msg.MessageId != "" && e != nil
 	for _, key := range pu.ops.routeKeys {
+		if pu.ops.confirm || custom {
+			err = pu.publishOnChannel(key)
+			if err != nil {
+				return
+			}
+			continue
+		}
 		envelope := &tcr.Envelope{
 			DeliveryMode: pu.msg.DeliveryMode,
 			Exchange:     pu.ex.ops.name,
@@ -123,9 +154,14 @@ func (pu *Publish) publish() (err error) {
 			Headers:      pu.msg.Headers,
 			Mandatory:    pu.ops.mandatory,
 			Immediate:    pu.ops.immediate,
+			Type:         pu.msg.Type,
+		}
+		id, e := uuid.Parse(pu.msg.MessageId)
+		if e != nil {
+			id = uuid.New()
 		}
 		letter := &tcr.Letter{
-			LetterID:   uuid.New(),
+			LetterID:   id,
 			RetryCount: uint32(pu.ops.maxRetryCount),
 			Body:       pu.msg.Body,
 			Envelope:   envelope,
@@ -141,3 +177,109 @@ func (pu *Publish) publish() (err error) {
 	}
 	return
 }
+
+// publishOnChannel publish on a new channel, wait confirm if enabled, retry only when channel/connection failed
+func (pu *Publish) publishOnChannel(key string) (err error) {
+	for i := 0; i <= pu.ops.maxRetryCount; i++ {
+		if i > 0 {
+			log.WithContext(pu.ops.ctx).WithError(err).Warn("failed to publish to %s(%s), retry...", pu.ex.ops.name, key)
+			select {
+			case <-pu.ops.ctx.Done():
+				err = errors.WithStack(pu.ops.ctx.Err())
+				return
+			case <-time.After(time.Duration(pu.ops.reconnectInterval) * time.Millisecond):
+			}
+		}
+		var ch *amqp.Channel
+		ch, err = pu.channel()
+		if err == nil {
+			if pu.ops.confirm {
+				err = pu.confirm(ch, key)
+			} else {
+				err = ch.Publish(pu.ex.ops.name, key, pu.ops.mandatory, pu.ops.immediate, pu.message())
+			}
+			_ = ch.Close()
+		}
+		if err == nil ||
+			errors.Is(err, ErrPublishNack) ||
+			errors.Is(err, ErrPublishReturned) ||
+			errors.Is(err, ErrPublishTimeout) ||
+			errors.Is(err, ErrClosed) ||
+			pu.ops.ctx.Err() != nil {
+			return
+		}
+	}
+	return
+}
+
+// channel get a new channel, wait at most publish timeout
+func (pu *Publish) channel() (ch *amqp.Channel, err error) {
+	ctx, cancel := context.WithTimeout(pu.ops.ctx, time.Duration(pu.ops.timeout)*time.Millisecond)
+	defer cancel()
+	ch, err = pu.ex.rb.channel(ctx, nil, pu.ops.confirm)
+	if err != nil {
+		err = errors.Wrapf(err, "get channel failed")
+	}
+	return
+}
+
+func (pu *Publish) confirm(ch *amqp.Channel, key string) (err error) {
+	confirms := ch.NotifyPublish(make(chan amqp.Confirmation, 1))
+	returns := ch.NotifyReturn(make(chan amqp.Return, 1))
+	err = ch.Publish(pu.ex.ops.name, key, pu.ops.mandatory, pu.ops.immediate, pu.message())
+	if err != nil {
+		return
+	}
+	timer := time.NewTimer(time.Duration(pu.ops.timeout) * time.Millisecond)
+	defer timer.Stop()
+	var returned *amqp.Return
+	for {
+		select {
+		case r := <-returns:
+			returned = &r
+		case c, ok := <-confirms:
+			if !ok {
+				err = errors.Errorf("channel closed before confirm")
+				return
+			}
+			if !c.Ack {
+				err = errors.Wrapf(ErrPublishNack, "exchange: %s, key: %s", pu.ex.ops.name, key)
+				return
+			}
+			// broker always sends basic.return before basic.ack
+			if returned == nil {
+				select {
+				case r := <-returns:
+					returned = &r
+				default:
+				}
+			}
+			if returned != nil {
+				err = errors.Wrapf(
+					ErrPublishReturned,
+					"exchange: %s, key: %s, code: %d, reason: %s",
+					pu.ex.ops.name, key, returned.ReplyCode, returned.ReplyText,
+				)
+			}
+			return
+		case <-timer.C:
+			err = errors.Wrapf(ErrPublishTimeout, "exchange: %s, key: %s", pu.ex.ops.name, key)
+			return
+		case <-pu.ops.ctx.Done():
+			err = errors.WithStack(pu.ops.ctx.Err())
+			return
+		}
+	}
+}
+
+// message msg with app id and a random uuid msg id if not set, same as tcr publisher
+func (pu *Publish) message() amqp.Publishing {
+	msg := pu.msg
+	if msg.MessageId == "" {
+		msg.MessageId = uuid.NewString()
+	}
+	if msg.AppId == "" {
+		msg.AppId = pu.ex.rb.poolConfig.ApplicationName
+	}
+	return msg
+}
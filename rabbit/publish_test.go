@@ -2,9 +2,12 @@ package rabbit
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 
+	"github.com/go-cinch/common/rabbit/rabbittest"
+	"github.com/streadway/amqp"
 	"google.golang.org/protobuf/types/known/emptypb"
 )
 
@@ -83,3 +86,101 @@ func TestExchange_PublishProto2(t *testing.T) {
 		t.Log(time.Now(), "send end", err)
 	}
 }
+
+func TestExchange_PublishConfirm(t *testing.T) {
+	rb := New(
+		uri,
+	)
+	if rb.Error != nil {
+		panic(rb.Error)
+	}
+	ex := rb.Exchange(
+		WithExchangeName("ex1"),
+		WithExchangeDeclare(false),
+	)
+	if ex.Error != nil {
+		panic(ex.Error)
+	}
+
+	err := ex.PublishJSON(
+		"{}",
+		WithPublishRouteKey("rt1"),
+		WithPublishConfirm(true),
+	)
+	t.Log(time.Now(), "send end", err)
+
+	err = ex.PublishJSON(
+		"{}",
+		WithPublishRouteKey("rt-not-bound"),
+		WithPublishMandatory(true),
+		WithPublishConfirm(true),
+	)
+	if !errors.Is(err, ErrPublishReturned) {
+		t.Errorf("expect returned err, got %v", err)
+	}
+}
+
+func TestPublish_publishOnChannel_brokerDown(t *testing.T) {
+	s, err := rabbittest.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rb := New(s.URI(), WithTimeout(1))
+	if rb.Error != nil {
+		t.Fatal(rb.Error)
+	}
+	defer rb.Close()
+	ex := rb.Exchange(WithExchangeName("ex1"))
+	s.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	pu := ex.beforePublish(
+		WithPublishCtx(ctx),
+		WithPublishRouteKey("rt1"),
+		WithPublishConfirm(true),
+		WithPublishTimeout(100),
+		WithPublishReconnectInterval(50),
+	)
+	done := make(chan error, 1)
+	go func() {
+		done <- pu.publishOnChannel("rt1")
+	}()
+	select {
+	case err = <-done:
+		if err == nil {
+			t.Error("expect publish failed while broker is down")
+		}
+	case <-time.After(3 * time.Second):
+		t.Error("expect publish gave up before ctx done")
+	}
+}
+
+func TestExchange_PublishByte_customMessageID(t *testing.T) {
+	s, err := rabbittest.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	rb := New(s.URI())
+	defer rb.Close()
+	ex := rb.Exchange(WithExchangeName("ex1"))
+	qu := ex.Queue(WithQueueName("q1"), WithQueueRouteKeys("rt1"))
+	if qu.Error != nil {
+		t.Fatal(qu.Error)
+	}
+	for _, id := range []string{"order-1", "4d9f9ec6-6f0a-4a4b-9b4e-1b5a2f0c7d11"} {
+		err = ex.PublishByte([]byte("{}"), WithPublishRouteKey("rt1"), WithPublishMessageID(id))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	var msgs []amqp.Publishing
+	for i := 0; i < 20 && len(msgs) < 2; i++ {
+		time.Sleep(50 * time.Millisecond)
+		msgs = s.Messages("q1")
+	}
+	if len(msgs) != 2 || msgs[0].MessageId != "order-1" || msgs[1].MessageId != "4d9f9ec6-6f0a-4a4b-9b4e-1b5a2f0c7d11" {
+		t.Errorf("expect msg ids kept, got %+v", msgs)
+	}
+}
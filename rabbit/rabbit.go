@@ -1,8 +1,9 @@
 package rabbit
 
 import (
+	"context"
 	"strings"
-	"sync/atomic"
+	"sync"
 	"time"
 
 	"github.com/go-cinch/common/log"
@@ -12,13 +13,31 @@ import (
 	"github.com/streadway/amqp"
 )
 
+var (
+	ErrClosed = errors.New("rabbit is closed")
+	// errStopped the caller gave up waiting channel
+	errStopped = errors.New("stopped")
+)
+
 type Rabbit struct {
 	ops        Options
 	pool       *tcr.ConnectionPool
 	poolConfig *tcr.PoolConfig
+	healthPool *tcr.ConnectionPool
 	healthHost *tcr.ConnectionHost
-	lost       int32
-	Error      error
+	health     Health
+	healthLock sync.RWMutex
+	// transient channels are opened on channelHost, it never blocks like pool.GetTransientChannel
+	channelPool *tcr.ConnectionPool
+	channelHost *tcr.ConnectionHost
+	// in-flight channel acquisitions, Close waits for them before shutdown
+	acquiring   sync.WaitGroup
+	acquireLock sync.Mutex
+	closed      bool
+	stop        chan struct{}
+	done        chan struct{}
+	once        sync.Once
+	Error       error
 }
 
 type Exchange struct {
@@ -73,35 +92,33 @@ func New(dsn string, options ...func(*Options)) (rb *Rabbit) {
 		rb.Error = err
 		return
 	}
-	healthPool, _ := tcr.NewConnectionPool(healthPoolConfig)
-	rb.healthHost, err = healthPool.GetConnection()
+	rb.pool = pool
+	rb.healthPool, err = tcr.NewConnectionPool(healthPoolConfig)
 	if err != nil {
 		rb.Error = err
 		return
 	}
-	rb.pool = pool
-	go rb.healthCheck()
-	return
-}
-
-func (rb *Rabbit) healthCheck() {
-	// InfiniteLoop: Stay here till we reconnect.
-	for {
-		ok := rb.healthHost.Connect()
-		if ok {
-			atomic.CompareAndSwapInt32(&rb.lost, 1, 0)
-		} else {
-			atomic.CompareAndSwapInt32(&rb.lost, 0, 1)
-		}
-		time.Sleep(time.Duration(rb.ops.healthCheckInterval) * time.Millisecond)
+	rb.healthHost, err = rb.healthPool.GetConnection()
+	if err != nil {
+		rb.Error = err
+		return
 	}
-}
-
-func (rb *Rabbit) Ping() (err error) {
-	if rb.lost == 1 {
-		err = errors.Errorf("connection maybe lost")
+	channelPoolConfig := *healthPoolConfig
+	channelPoolConfig.ApplicationName = strings.Join([]string{name, "ch"}, "-")
+	rb.channelPool, err = tcr.NewConnectionPool(&channelPoolConfig)
+	if err != nil {
+		rb.Error = err
 		return
 	}
+	rb.channelHost, err = rb.channelPool.GetConnection()
+	if err != nil {
+		rb.Error = err
+		return
+	}
+	rb.stop = make(chan struct{})
+	rb.done = make(chan struct{})
+	rb.check()
+	go rb.healthCheck()
 	return
 }
 
@@ -306,3 +323,98 @@ Loop:
 	}
 	return
 }
+
+// channel get a transient channel, it is tried only when connection is healthy,
+// and this gives up once ctx done, stop closed or rabbit closed
+func (rb *Rabbit) channel(ctx context.Context, stop <-chan struct{}, confirm bool) (ch *amqp.Channel, err error) {
+	var res chan channelResult
+	for {
+		if res == nil && rb.Ping() == nil {
+			res, err = rb.acquire(confirm)
+			if err != nil {
+				return
+			}
+		}
+		select {
+		case r := <-res:
+			if r.err == nil {
+				ch = r.ch
+				return
+			}
+			res = nil
+			log.WithContext(rb.ops.ctx).WithError(r.err).Warn("get channel failed, retry...")
+			continue
+		case <-ctx.Done():
+			err = errors.WithStack(ctx.Err())
+		case <-stop:
+			err = errors.WithStack(errStopped)
+		case <-rb.stop:
+			err = errors.WithStack(ErrClosed)
+		case <-time.After(time.Duration(rb.ops.healthCheckInterval) * time.Millisecond):
+		}
+		if err != nil {
+			break
+		}
+	}
+	if res != nil {
+		// close the channel got after giving up
+		go func(res <-chan channelResult) {
+			if r := <-res; r.err == nil {
+				_ = r.ch.Close()
+			}
+		}(res)
+	}
+	return
+}
+
+type channelResult struct {
+	ch  *amqp.Channel
+	err error
+}
+
+// acquire open a channel in background, it's tracked so that Close waits for it
+func (rb *Rabbit) acquire(confirm bool) (res chan channelResult, err error) {
+	rb.acquireLock.Lock()
+	defer rb.acquireLock.Unlock()
+	if rb.closed {
+		err = errors.WithStack(ErrClosed)
+		return
+	}
+	res = make(chan channelResult, 1)
+	rb.acquiring.Add(1)
+	go func() {
+		defer rb.acquiring.Done()
+		var r channelResult
+		r.ch, r.err = rb.transientChannel(confirm)
+		res <- r
+	}()
+	return
+}
+
+// transientChannel open a channel on channelHost, reconnect at most once, so it returns within connection timeout
+func (rb *Rabbit) transientChannel(confirm bool) (ch *amqp.Channel, err error) {
+	ok := rb.channelHost.ConnectWithErrorHandler(func(e error) {
+		err = e
+	})
+	if !ok {
+		if err == nil {
+			err = errors.New("connect failed")
+		}
+		err = errors.WithStack(err)
+		return
+	}
+	ch, err = rb.channelHost.Connection.Channel()
+	if err != nil {
+		err = errors.WithStack(err)
+		return
+	}
+	if confirm {
+		err = ch.Confirm(false)
+		if err != nil {
+			_ = ch.Close()
+			ch = nil
+			err = errors.WithStack(err)
+		}
+	}
+	return
+}
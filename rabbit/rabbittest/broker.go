@@ -0,0 +1,1032 @@
+package rabbittest
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/streadway/amqp"
+)
+
+type exchange struct {
+	name       string
+	kind       string
+	durable    bool
+	autoDelete bool
+	internal   bool
+	args       amqp.Table
+	bindings   []*binding
+}
+
+type binding struct {
+	queue string
+	key   string
+	args  amqp.Table
+}
+
+type queue struct {
+	name       string
+	durable    bool
+	exclusive  bool
+	autoDelete bool
+	args       amqp.Table
+	owner      *conn
+	msgs       []*message
+	consumers  []*consumer
+	next       int
+	deleted    bool
+	timer      *time.Timer
+}
+
+type message struct {
+	exchange    string
+	key         string
+	pub         amqp.Publishing
+	redelivered bool
+	// expireAt zero means never
+	expireAt time.Time
+}
+
+// handle a method of channel, the lock is held
+func (s *Server) handle(ch *channel, id uint32, d *decoder) *amqpError {
+	switch id {
+	case channelClose:
+		s.closeChannel(ch)
+		delete(ch.c.channels, ch.id)
+		ch.c.send(ch.id, newMethod(channelCloseOk))
+	case channelCloseOk:
+	case channelFlow:
+		active := d.octet()&1 != 0
+		ch.paused = !active
+		ch.c.send(ch.id, newMethod(channelFlowOk).bits(active))
+		s.dispatchChannel(ch)
+	case exchangeDeclare:
+		return s.exchangeDeclare(ch, d)
+	case exchangeDelete:
+		return s.exchangeDelete(ch, d)
+	case queueDeclare:
+		return s.queueDeclare(ch, d)
+	case queueBind:
+		return s.queueBind(ch, d)
+	case queueUnbind:
+		return s.queueUnbind(ch, d)
+	case queuePurge:
+		return s.queuePurge(ch, d)
+	case queueDelete:
+		return s.queueDelete(ch, d)
+	case basicQos:
+		d.long()
+		count := d.short()
+		if d.octet()&1 != 0 {
+			ch.global = count
+		} else {
+			ch.prefetch = count
+		}
+		ch.c.send(ch.id, newMethod(basicQosOk))
+		s.dispatchChannel(ch)
+	case basicConsume:
+		return s.basicConsume(ch, d)
+	case basicCancel:
+		tag := d.shortstr()
+		noWait := d.octet()&1 != 0
+		if co, ok := ch.consumers[tag]; ok {
+			s.cancel(co, false)
+		}
+		if !noWait {
+			ch.c.send(ch.id, newMethod(basicCancelOk).shortstr(tag))
+		}
+	case basicPublish:
+		d.short()
+		ch.pending = &pending{
+			exchange:  d.shortstr(),
+			key:       d.shortstr(),
+			mandatory: d.octet()&1 != 0,
+		}
+	case basicGet:
+		return s.basicGet(ch, d)
+	case basicAck:
+		tag := d.longlong()
+		return s.ack(ch, tag, d.octet()&1 != 0)
+	case basicNack:
+		tag := d.longlong()
+		bits := d.octet()
+		return s.nack(ch, tag, bits&1 != 0, bits&2 != 0)
+	case basicReject:
+		tag := d.longlong()
+		return s.nack(ch, tag, false, d.octet()&1 != 0)
+	case basicRecover, basicRecoverAsync:
+		d.octet()
+		s.recover(ch)
+		if id == basicRecover {
+			ch.c.send(ch.id, newMethod(basicRecoverOk))
+		}
+	case confirmSelect:
+		ch.confirm = true
+		if d.octet()&1 == 0 {
+			ch.c.send(ch.id, newMethod(confirmSelectOk))
+		}
+	default:
+		return channelErr(notImplemented, "NOT_IMPLEMENTED - method %d.%d is not supported by rabbittest", id>>16, id&0xffff)
+	}
+	return nil
+}
+
+func (s *Server) exchangeDeclare(ch *channel, d *decoder) *amqpError {
+	d.short()
+	name := d.shortstr()
+	kind := d.shortstr()
+	bits := d.octet()
+	args := d.table()
+	if d.err != nil {
+		return nil
+	}
+	passive, durable, autoDelete, internal, noWait := bits&1 != 0, bits&2 != 0, bits&4 != 0, bits&8 != 0, bits&16 != 0
+	ex, ok := s.exchanges[name]
+	switch {
+	case passive:
+		if !ok {
+			return channelErr(notFound, "NOT_FOUND - no exchange '%s' in vhost '/'", name)
+		}
+	case ok:
+		what := "exchange '" + name + "'"
+		switch {
+		case ex.kind != kind:
+			return inequivalent("type", what, kind, ex.kind)
+		case ex.durable != durable:
+			return inequivalent("durable", what, durable, ex.durable)
+		case ex.autoDelete != autoDelete:
+			return inequivalent("auto_delete", what, autoDelete, ex.autoDelete)
+		case ex.internal != internal:
+			return inequivalent("internal", what, internal, ex.internal)
+		}
+		if e := argsEquivalent(what, ex.args, args); e != nil {
+			return e
+		}
+	default:
+		if name == "" || strings.HasPrefix(name, "amq.") {
+			return channelErr(accessRefused, "ACCESS_REFUSED - exchange name '%s' contains reserved prefix 'amq.*'", name)
+		}
+		switch kind {
+		case amqp.ExchangeDirect, amqp.ExchangeFanout, amqp.ExchangeTopic, amqp.ExchangeHeaders:
+		default:
+			return connectionErr(commandInvalid, "COMMAND_INVALID - invalid exchange type '%s'", kind)
+		}
+		s.exchanges[name] = &exchange{
+			name:       name,
+			kind:       kind,
+			durable:    durable,
+			autoDelete: autoDelete,
+			internal:   internal,
+			args:       args,
+		}
+	}
+	if !noWait {
+		ch.c.send(ch.id, newMethod(exchangeDeclareOk))
+	}
+	return nil
+}
+
+func (s *Server) exchangeDelete(ch *channel, d *decoder) *amqpError {
+	d.short()
+	name := d.shortstr()
+	bits := d.octet()
+	ifUnused, noWait := bits&1 != 0, bits&2 != 0
+	if name == "" || strings.HasPrefix(name, "amq.") {
+		return channelErr(accessRefused, "ACCESS_REFUSED - operation not permitted on exchange '%s'", name)
+	}
+	// delete a missing exchange is ok like rabbitmq
+	if ex, ok := s.exchanges[name]; ok {
+		if ifUnused && len(ex.bindings) > 0 {
+			return channelErr(preconditionFailed, "PRECONDITION_FAILED - exchange '%s' in vhost '/' in use", name)
+		}
+		delete(s.exchanges, name)
+	}
+	if !noWait {
+		ch.c.send(ch.id, newMethod(exchangeDeleteOk))
+	}
+	return nil
+}
+
+func (s *Server) queueDeclare(ch *channel, d *decoder) *amqpError {
+	d.short()
+	name := d.shortstr()
+	bits := d.octet()
+	args := d.table()
+	if d.err != nil {
+		return nil
+	}
+	passive, durable, exclusive, autoDelete, noWait := bits&1 != 0, bits&2 != 0, bits&4 != 0, bits&8 != 0, bits&16 != 0
+	q, ok := s.queues[name]
+	switch {
+	case passive:
+		var e *amqpError
+		q, e = s.queue(ch, name)
+		if e != nil {
+			return e
+		}
+	case ok:
+		if _, e := s.queue(ch, name); e != nil {
+			return e
+		}
+		what := "queue '" + name + "'"
+		switch {
+		case q.durable != durable:
+			return inequivalent("durable", what, durable, q.durable)
+		case q.autoDelete != autoDelete:
+			return inequivalent("auto_delete", what, autoDelete, q.autoDelete)
+		}
+		if e := argsEquivalent(what, q.args, args); e != nil {
+			return e
+		}
+	default:
+		if name == "" {
+			name = "amq.gen-" + uuid.NewString()
+		} else if strings.HasPrefix(name, "amq.") {
+			return channelErr(accessRefused, "ACCESS_REFUSED - queue name '%s' contains reserved prefix 'amq.*'", name)
+		}
+		for _, k := range []string{"x-message-ttl", "x-max-length"} {
+			if v, ok := args[k]; ok {
+				if i, ok := toInt64(v); !ok || i < 0 {
+					return channelErr(preconditionFailed, "PRECONDITION_FAILED - invalid arg '%s' for queue '%s' in vhost '/': %v", k, name, v)
+				}
+			}
+		}
+		if v, ok := args["x-dead-letter-exchange"]; ok {
+			if _, ok = v.(string); !ok {
+				return channelErr(preconditionFailed, "PRECONDITION_FAILED - invalid arg 'x-dead-letter-exchange' for queue '%s' in vhost '/': %v", name, v)
+			}
+		}
+		q = &queue{
+			name:       name,
+			durable:    durable,
+			exclusive:  exclusive,
+			autoDelete: autoDelete,
+			args:       args,
+		}
+		if exclusive {
+			q.owner = ch.c
+		}
+		s.queues[name] = q
+	}
+	if !noWait {
+		ch.c.send(ch.id, newMethod(queueDeclareOk).
+			shortstr(q.name).
+			long(uint32(len(q.msgs))).
+			long(uint32(len(q.consumers))))
+	}
+	return nil
+}
+
+func (s *Server) queueBind(ch *channel, d *decoder) *amqpError {
+	d.short()
+	name := d.shortstr()
+	exName := d.shortstr()
+	key := d.shortstr()
+	noWait := d.octet()&1 != 0
+	args := d.table()
+	if d.err != nil {
+		return nil
+	}
+	if _, e := s.queue(ch, name); e != nil {
+		return e
+	}
+	if exName == "" {
+		return channelErr(accessRefused, "ACCESS_REFUSED - operation not permitted on the default exchange")
+	}
+	ex, ok := s.exchanges[exName]
+	if !ok {
+		return channelErr(notFound, "NOT_FOUND - no exchange '%s' in vhost '/'", exName)
+	}
+	exists := false
+	for _, b := range ex.bindings {
+		if b.queue == name && b.key == key && argsEquivalent("", b.args, args) == nil {
+			exists = true
+			break
+		}
+	}
+	if !exists {
+		ex.bindings = append(ex.bindings, &binding{
+			queue: name,
+			key:   key,
+			args:  args,
+		})
+	}
+	if !noWait {
+		ch.c.send(ch.id, newMethod(queueBindOk))
+	}
+	return nil
+}
+
+func (s *Server) queueUnbind(ch *channel, d *decoder) *amqpError {
+	d.short()
+	name := d.shortstr()
+	exName := d.shortstr()
+	key := d.shortstr()
+	args := d.table()
+	if d.err != nil {
+		return nil
+	}
+	if _, e := s.queue(ch, name); e != nil {
+		return e
+	}
+	ex, ok := s.exchanges[exName]
+	if !ok {
+		return channelErr(notFound, "NOT_FOUND - no exchange '%s' in vhost '/'", exName)
+	}
+	s.unbind(ex, func(b *binding) bool {
+		return b.queue == name && b.key == key && argsEquivalent("", b.args, args) == nil
+	})
+	ch.c.send(ch.id, newMethod(queueUnbindOk))
+	return nil
+}
+
+func (s *Server) queuePurge(ch *channel, d *decoder) *amqpError {
+	d.short()
+	name := d.shortstr()
+	noWait := d.octet()&1 != 0
+	q, e := s.queue(ch, name)
+	if e != nil {
+		return e
+	}
+	count := len(q.msgs)
+	q.msgs = nil
+	q.stopTimer()
+	if !noWait {
+		ch.c.send(ch.id, newMethod(queuePurgeOk).long(uint32(count)))
+	}
+	return nil
+}
+
+func (s *Server) queueDelete(ch *channel, d *decoder) *amqpError {
+	d.short()
+	name := d.shortstr()
+	bits := d.octet()
+	ifUnused, ifEmpty, noWait := bits&1 != 0, bits&2 != 0, bits&4 != 0
+	count := 0
+	// delete a missing queue is ok like rabbitmq
+	if _, ok := s.queues[name]; ok {
+		q, e := s.queue(ch, name)
+		if e != nil {
+			return e
+		}
+		if ifUnused && len(q.consumers) > 0 {
+			return channelErr(preconditionFailed, "PRECONDITION_FAILED - queue '%s' in vhost '/' in use", name)
+		}
+		if ifEmpty && len(q.msgs) > 0 {
+			return channelErr(preconditionFailed, "PRECONDITION_FAILED - queue '%s' in vhost '/' not empty", name)
+		}
+		count = len(q.msgs)
+		s.deleteQueue(q)
+	}
+	if !noWait {
+		ch.c.send(ch.id, newMethod(queueDeleteOk).long(uint32(count)))
+	}
+	return nil
+}
+
+func (s *Server) basicConsume(ch *channel, d *decoder) *amqpError {
+	d.short()
+	name := d.shortstr()
+	tag := d.shortstr()
+	bits := d.octet()
+	d.table()
+	if d.err != nil {
+		return nil
+	}
+	noAck, exclusive, noWait := bits&2 != 0, bits&4 != 0, bits&8 != 0
+	q, e := s.queue(ch, name)
+	if e != nil {
+		return e
+	}
+	for _, co := range q.consumers {
+		if exclusive || co.exclusive {
+			return channelErr(accessRefused, "ACCESS_REFUSED - queue '%s' in vhost '/' in exclusive use", name)
+		}
+	}
+	if tag == "" {
+		tag = "amq.ctag-" + uuid.NewString()
+	}
+	if _, ok := ch.consumers[tag]; ok {
+		return connectionErr(notAllowed, "NOT_ALLOWED - attempt to reuse consumer tag '%s'", tag)
+	}
+	co := &consumer{
+		tag:       tag,
+		ch:        ch,
+		q:         q,
+		noAck:     noAck,
+		exclusive: exclusive,
+		prefetch:  ch.prefetch,
+	}
+	ch.consumers[tag] = co
+	q.consumers = append(q.consumers, co)
+	if !noWait {
+		ch.c.send(ch.id, newMethod(basicConsumeOk).shortstr(tag))
+	}
+	s.dispatch(q)
+	return nil
+}
+
+func (s *Server) basicGet(ch *channel, d *decoder) *amqpError {
+	d.short()
+	name := d.shortstr()
+	noAck := d.octet()&1 != 0
+	q, e := s.queue(ch, name)
+	if e != nil {
+		return e
+	}
+	s.expire(q)
+	if len(q.msgs) == 0 {
+		ch.c.send(ch.id, newMethod(basicGetEmpty).shortstr(""))
+		return nil
+	}
+	m := q.pop()
+	ch.deliveryTag++
+	if !noAck {
+		ch.unacked = append(ch.unacked, &unacked{
+			tag: ch.deliveryTag,
+			msg: m,
+			q:   q,
+		})
+	}
+	ch.c.sendContent(ch.id, newMethod(basicGetOk).
+		longlong(ch.deliveryTag).
+		bits(m.redelivered).
+		shortstr(m.exchange).
+		shortstr(m.key).
+		long(uint32(len(q.msgs))), m.pub)
+	s.schedule(q)
+	return nil
+}
+
+// publish route a received content to queues
+func (s *Server) publish(ch *channel, p *pending) *amqpError {
+	ex, ok := s.exchanges[p.exchange]
+	if !ok {
+		return channelErr(notFound, "NOT_FOUND - no exchange '%s' in vhost '/'", p.exchange)
+	}
+	if ex.internal {
+		return channelErr(accessRefused, "ACCESS_REFUSED - cannot publish to internal exchange '%s' in vhost '/'", p.exchange)
+	}
+	if p.pub.Expiration != "" {
+		if i, err := strconv.ParseInt(p.pub.Expiration, 10, 64); err != nil || i < 0 {
+			return channelErr(preconditionFailed, "PRECONDITION_FAILED - invalid expiration '%s'", p.pub.Expiration)
+		}
+	}
+	qs := s.route(ex, p.key, p.pub.Headers)
+	if len(qs) == 0 && p.mandatory {
+		ch.c.sendContent(ch.id, newMethod(basicReturn).
+			short(noRoute).
+			shortstr("NO_ROUTE").
+			shortstr(p.exchange).
+			shortstr(p.key), p.pub)
+	}
+	for _, q := range qs {
+		s.enqueue(q, &message{
+			exchange: p.exchange,
+			key:      p.key,
+			pub:      p.pub,
+		})
+	}
+	if ch.confirm {
+		ch.publishSeq++
+		ch.c.send(ch.id, newMethod(basicAck).longlong(ch.publishSeq).bits(false))
+	}
+	return nil
+}
+
+// route find queues of key and headers, each queue is returned once
+func (s *Server) route(ex *exchange, key string, headers amqp.Table) (qs []*queue) {
+	if ex.name == "" {
+		if q, ok := s.queues[key]; ok {
+			qs = append(qs, q)
+		}
+		return
+	}
+	seen := make(map[string]struct{})
+	for _, b := range ex.bindings {
+		var match bool
+		switch ex.kind {
+		case amqp.ExchangeDirect:
+			match = b.key == key
+		case amqp.ExchangeFanout:
+			match = true
+		case amqp.ExchangeTopic:
+			match = topicMatch(words(b.key), words(key))
+		case amqp.ExchangeHeaders:
+			match = headersMatch(b.args, headers)
+		}
+		if !match {
+			continue
+		}
+		if _, ok := seen[b.queue]; ok {
+			continue
+		}
+		seen[b.queue] = struct{}{}
+		if q, ok := s.queues[b.queue]; ok {
+			qs = append(qs, q)
+		}
+	}
+	return
+}
+
+func (s *Server) enqueue(q *queue, m *message) {
+	ttl, hasTTL := q.ttl(m)
+	if hasTTL {
+		m.expireAt = time.Now().Add(time.Duration(ttl) * time.Millisecond)
+	}
+	if len(q.msgs) == 0 {
+		if co := q.available(); co != nil {
+			s.deliver(co, m)
+			return
+		}
+	}
+	// ttl 0 means expire unless it can be delivered immediately
+	if hasTTL && ttl == 0 {
+		s.deadLetter(q, m, "expired")
+		return
+	}
+	q.msgs = append(q.msgs, m)
+	if v, ok := q.args["x-max-length"]; ok {
+		max, _ := toInt64(v)
+		for int64(len(q.msgs)) > max {
+			s.deadLetter(q, q.pop(), "maxlen")
+		}
+	}
+	s.dispatch(q)
+}
+
+// dispatch deliver ready messages to consumers which have capacity
+func (s *Server) dispatch(q *queue) {
+	if q.deleted {
+		return
+	}
+	s.expire(q)
+	for len(q.msgs) > 0 {
+		co := q.available()
+		if co == nil {
+			break
+		}
+		s.deliver(co, q.pop())
+		s.expire(q)
+	}
+	s.schedule(q)
+}
+
+func (s *Server) dispatchChannel(ch *channel) {
+	for _, co := range ch.consumers {
+		s.dispatch(co.q)
+	}
+}
+
+func (s *Server) deliver(co *consumer, m *message) {
+	ch := co.ch
+	ch.deliveryTag++
+	if !co.noAck {
+		ch.unacked = append(ch.unacked, &unacked{
+			tag: ch.deliveryTag,
+			msg: m,
+			q:   co.q,
+			co:  co,
+		})
+		co.unacked++
+	}
+	ch.c.sendContent(ch.id, newMethod(basicDeliver).
+		shortstr(co.tag).
+		longlong(ch.deliveryTag).
+		bits(m.redelivered).
+		shortstr(m.exchange).
+		shortstr(m.key), m.pub)
+}
+
+// expire dead letter expired messages at the head of queue like rabbitmq
+func (s *Server) expire(q *queue) {
+	now := time.Now()
+	for len(q.msgs) > 0 && !q.msgs[0].expireAt.IsZero() && !now.Before(q.msgs[0].expireAt) {
+		s.deadLetter(q, q.pop(), "expired")
+	}
+}
+
+// schedule expire the head of queue when its ttl is reached
+func (s *Server) schedule(q *queue) {
+	q.stopTimer()
+	if s.closed || q.deleted || len(q.msgs) == 0 || q.msgs[0].expireAt.IsZero() {
+		return
+	}
+	q.timer = time.AfterFunc(time.Until(q.msgs[0].expireAt), func() {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		if s.closed {
+			return
+		}
+		s.dispatch(q)
+	})
+}
+
+// deadLetter republish m to dead letter exchange of q with x-death header, m is dropped if q has no dead letter exchange
+func (s *Server) deadLetter(q *queue, m *message, reason string) {
+	v, ok := q.args["x-dead-letter-exchange"]
+	if !ok {
+		return
+	}
+	name, _ := v.(string)
+	ex, ok := s.exchanges[name]
+	if !ok {
+		return
+	}
+	key := m.key
+	if v, ok := q.args["x-dead-letter-routing-key"].(string); ok {
+		key = v
+	}
+	headers := make(amqp.Table, len(m.pub.Headers)+4)
+	for k, v := range m.pub.Headers {
+		headers[k] = v
+	}
+	deaths, _ := headers["x-death"].([]interface{})
+	var entry amqp.Table
+	for i, item := range deaths {
+		t, ok := item.(amqp.Table)
+		if ok && t["queue"] == q.name && t["reason"] == reason {
+			entry = make(amqp.Table, len(t))
+			for k, v := range t {
+				entry[k] = v
+			}
+			count, _ := toInt64(entry["count"])
+			entry["count"] = count + 1
+			deaths = append(deaths[:i:i], deaths[i+1:]...)
+			break
+		}
+	}
+	if entry == nil {
+		entry = amqp.Table{
+			"count":        int64(1),
+			"reason":       reason,
+			"queue":        q.name,
+			"exchange":     m.exchange,
+			"routing-keys": []interface{}{m.key},
+		}
+		if m.pub.Expiration != "" {
+			entry["original-expiration"] = m.pub.Expiration
+		}
+	}
+	entry["time"] = time.Now()
+	deaths = append([]interface{}{entry}, deaths...)
+	headers["x-death"] = deaths
+	if _, ok := headers["x-first-death-reason"]; !ok {
+		headers["x-first-death-reason"] = reason
+		headers["x-first-death-queue"] = q.name
+		headers["x-first-death-exchange"] = m.exchange
+	}
+	pub := m.pub
+	pub.Headers = headers
+	pub.Expiration = ""
+	for _, target := range s.route(ex, key, headers) {
+		if cycle(deaths, target.name) {
+			continue
+		}
+		s.enqueue(target, &message{
+			exchange: name,
+			key:      key,
+			pub:      pub,
+		})
+	}
+}
+
+// cycle message dead lettered back to a queue without being rejected, it's dropped like rabbitmq
+func cycle(deaths []interface{}, target string) bool {
+	for _, item := range deaths {
+		t, _ := item.(amqp.Table)
+		if t["reason"] == "rejected" {
+			return false
+		}
+		if t["queue"] == target {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) ack(ch *channel, tag uint64, multiple bool) *amqpError {
+	items, e := ch.take(tag, multiple)
+	if e != nil {
+		return e
+	}
+	for _, item := range items {
+		if item.co != nil {
+			item.co.unacked--
+		}
+	}
+	s.dispatchChannel(ch)
+	return nil
+}
+
+func (s *Server) nack(ch *channel, tag uint64, multiple, requeue bool) *amqpError {
+	items, e := ch.take(tag, multiple)
+	if e != nil {
+		return e
+	}
+	for _, item := range items {
+		if item.co != nil {
+			item.co.unacked--
+		}
+	}
+	if requeue {
+		s.requeue(items)
+	} else {
+		for _, item := range items {
+			s.deadLetter(item.q, item.msg, "rejected")
+		}
+	}
+	s.dispatchChannel(ch)
+	return nil
+}
+
+func (s *Server) recover(ch *channel) {
+	items := ch.unacked
+	ch.unacked = nil
+	for _, item := range items {
+		if item.co != nil {
+			item.co.unacked--
+		}
+	}
+	s.requeue(items)
+	s.dispatchChannel(ch)
+}
+
+// requeue put messages back to the head of their queues in delivery order
+func (s *Server) requeue(items []*unacked) {
+	for i := len(items) - 1; i >= 0; i-- {
+		q := items[i].q
+		if q.deleted {
+			continue
+		}
+		m := items[i].msg
+		m.redelivered = true
+		q.msgs = append([]*message{m}, q.msgs...)
+	}
+	for _, item := range items {
+		s.dispatch(item.q)
+	}
+}
+
+// cancel remove consumer, notify client if the queue is deleted
+func (s *Server) cancel(co *consumer, notify bool) {
+	delete(co.ch.consumers, co.tag)
+	q := co.q
+	for i, item := range q.consumers {
+		if item == co {
+			q.consumers = append(q.consumers[:i:i], q.consumers[i+1:]...)
+			break
+		}
+	}
+	if notify {
+		co.ch.c.send(co.ch.id, newMethod(basicCancel).shortstr(co.tag).bits(true))
+	}
+	if q.autoDelete && len(q.consumers) == 0 && !q.deleted {
+		s.deleteQueue(q)
+	}
+}
+
+func (s *Server) deleteQueue(q *queue) {
+	q.deleted = true
+	q.stopTimer()
+	delete(s.queues, q.name)
+	for _, co := range append([]*consumer(nil), q.consumers...) {
+		s.cancel(co, !co.ch.closing)
+	}
+	for _, ex := range s.exchanges {
+		s.unbind(ex, func(b *binding) bool {
+			return b.queue == q.name
+		})
+	}
+}
+
+// unbind remove bindings matched, auto delete exchange is deleted after its last binding removed
+func (s *Server) unbind(ex *exchange, match func(*binding) bool) {
+	if len(ex.bindings) == 0 {
+		return
+	}
+	bindings := ex.bindings[:0:0]
+	for _, b := range ex.bindings {
+		if !match(b) {
+			bindings = append(bindings, b)
+		}
+	}
+	ex.bindings = bindings
+	if ex.autoDelete && len(ex.bindings) == 0 {
+		delete(s.exchanges, ex.name)
+	}
+}
+
+// closeChannel cancel consumers and requeue unacked messages
+func (s *Server) closeChannel(ch *channel) {
+	for _, co := range ch.consumers {
+		s.cancel(co, false)
+	}
+	items := ch.unacked
+	ch.unacked = nil
+	ch.pending = nil
+	s.requeue(items)
+}
+
+// queue find queue which can be accessed by ch
+func (s *Server) queue(ch *channel, name string) (q *queue, e *amqpError) {
+	q, ok := s.queues[name]
+	if !ok {
+		e = channelErr(notFound, "NOT_FOUND - no queue '%s' in vhost '/'", name)
+		return
+	}
+	if q.exclusive && q.owner != ch.c {
+		e = channelErr(resourceLocked, "RESOURCE_LOCKED - cannot obtain exclusive access to locked queue '%s' in vhost '/'", name)
+	}
+	return
+}
+
+// take remove unacked deliveries of tag, tag 0 with multiple means all
+func (ch *channel) take(tag uint64, multiple bool) (items []*unacked, e *amqpError) {
+	rest := ch.unacked[:0:0]
+	for _, item := range ch.unacked {
+		if item.tag == tag || (multiple && (tag == 0 || item.tag < tag)) {
+			items = append(items, item)
+			continue
+		}
+		rest = append(rest, item)
+	}
+	if len(items) == 0 && (tag != 0 || !multiple) {
+		e = channelErr(preconditionFailed, "PRECONDITION_FAILED - unknown delivery tag %d", tag)
+		return
+	}
+	ch.unacked = rest
+	return
+}
+
+func (q *queue) pop() *message {
+	m := q.msgs[0]
+	q.msgs[0] = nil
+	q.msgs = q.msgs[1:]
+	return m
+}
+
+// available find a consumer which has capacity by round robin
+func (q *queue) available() *consumer {
+	for i := range q.consumers {
+		j := (q.next + i) % len(q.consumers)
+		co := q.consumers[j]
+		if co.ready() {
+			q.next = (j + 1) % len(q.consumers)
+			return co
+		}
+	}
+	return nil
+}
+
+// ttl the smaller one of queue x-message-ttl and message expiration
+func (q *queue) ttl(m *message) (ttl int64, ok bool) {
+	if v, exists := q.args["x-message-ttl"]; exists {
+		ttl, ok = toInt64(v)
+	}
+	if m.pub.Expiration != "" {
+		if i, err := strconv.ParseInt(m.pub.Expiration, 10, 64); err == nil && (!ok || i < ttl) {
+			ttl, ok = i, true
+		}
+	}
+	return
+}
+
+func (q *queue) stopTimer() {
+	if q.timer != nil {
+		q.timer.Stop()
+		q.timer = nil
+	}
+}
+
+func (co *consumer) ready() bool {
+	if co.ch.paused || co.ch.closing {
+		return false
+	}
+	if co.noAck {
+		return true
+	}
+	if co.prefetch > 0 && co.unacked >= int(co.prefetch) {
+		return false
+	}
+	if co.ch.global > 0 && len(co.ch.unacked) >= int(co.ch.global) {
+		return false
+	}
+	return true
+}
+
+func words(key string) []string {
+	if key == "" {
+		return nil
+	}
+	return strings.Split(key, ".")
+}
+
+// topicMatch * matches exactly one word, # matches zero or more words
+func topicMatch(pattern, key []string) bool {
+	if len(pattern) == 0 {
+		return len(key) == 0
+	}
+	switch pattern[0] {
+	case "#":
+		for i := 0; i <= len(key); i++ {
+			if topicMatch(pattern[1:], key[i:]) {
+				return true
+			}
+		}
+		return false
+	case "*":
+		return len(key) > 0 && topicMatch(pattern[1:], key[1:])
+	}
+	return len(key) > 0 && pattern[0] == key[0] && topicMatch(pattern[1:], key[1:])
+}
+
+// headersMatch x-match all(default) or any, arguments start with x- are ignored, void value matches any value
+func headersMatch(args, headers amqp.Table) bool {
+	mode, _ := args["x-match"].(string)
+	matchAny := strings.HasPrefix(mode, "any")
+	for k, v := range args {
+		if strings.HasPrefix(k, "x-") {
+			continue
+		}
+		hv, ok := headers[k]
+		hit := ok && (v == nil || equalValue(v, hv))
+		if matchAny && hit {
+			return true
+		}
+		if !matchAny && !hit {
+			return false
+		}
+	}
+	return !matchAny
+}
+
+func inequivalent(arg, what string, received, current interface{}) *amqpError {
+	return channelErr(preconditionFailed, "PRECONDITION_FAILED - inequivalent arg '%s' for %s in vhost '/': received '%v' but current is '%v'", arg, what, received, current)
+}
+
+func argsEquivalent(what string, current, received amqp.Table) *amqpError {
+	keys := make([]string, 0, len(current)+len(received))
+	for k := range current {
+		keys = append(keys, k)
+	}
+	for k := range received {
+		if _, ok := current[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		a, aok := current[k]
+		b, bok := received[k]
+		if aok != bok || !equalValue(a, b) {
+			return inequivalent(k, what, b, a)
+		}
+	}
+	return nil
+}
+
+// equalValue integers of different types are equal if their values are equal
+func equalValue(a, b interface{}) bool {
+	if x, ok := toInt64(a); ok {
+		if y, ok := toInt64(b); ok {
+			return x == y
+		}
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func toInt64(v interface{}) (int64, bool) {
+	switch v := v.(type) {
+	case int:
+		return int64(v), true
+	case int8:
+		return int64(v), true
+	case int16:
+		return int64(v), true
+	case int32:
+		return int64(v), true
+	case int64:
+		return v, true
+	case uint8:
+		return int64(v), true
+	case uint16:
+		return int64(v), true
+	case uint32:
+		return int64(v), true
+	case uint64:
+		return int64(v), true
+	case float32:
+		if float32(int64(v)) == v {
+			return int64(v), true
+		}
+	case float64:
+		if float64(int64(v)) == v {
+			return int64(v), true
+		}
+	}
+	return 0, false
+}
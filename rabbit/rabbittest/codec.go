@@ -0,0 +1,493 @@
+package rabbittest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/streadway/amqp"
+)
+
+const (
+	frameMethod    = 1
+	frameHeader    = 2
+	frameBody      = 3
+	frameHeartbeat = 8
+	frameEnd       = 0xCE
+
+	// frameMax frame size proposed by server, same as rabbitmq
+	frameMax = 131072
+)
+
+// method id is class id << 16 | method id
+const (
+	connectionStart    = 10<<16 | 10
+	connectionStartOk  = 10<<16 | 11
+	connectionTune     = 10<<16 | 30
+	connectionTuneOk   = 10<<16 | 31
+	connectionOpen     = 10<<16 | 40
+	connectionOpenOk   = 10<<16 | 41
+	connectionClose    = 10<<16 | 50
+	connectionCloseOk  = 10<<16 | 51
+	channelOpen        = 20<<16 | 10
+	channelOpenOk      = 20<<16 | 11
+	channelFlow        = 20<<16 | 20
+	channelFlowOk      = 20<<16 | 21
+	channelClose       = 20<<16 | 40
+	channelCloseOk     = 20<<16 | 41
+	exchangeDeclare    = 40<<16 | 10
+	exchangeDeclareOk  = 40<<16 | 11
+	exchangeDelete     = 40<<16 | 20
+	exchangeDeleteOk   = 40<<16 | 21
+	queueDeclare       = 50<<16 | 10
+	queueDeclareOk     = 50<<16 | 11
+	queueBind          = 50<<16 | 20
+	queueBindOk        = 50<<16 | 21
+	queuePurge         = 50<<16 | 30
+	queuePurgeOk       = 50<<16 | 31
+	queueDelete        = 50<<16 | 40
+	queueDeleteOk      = 50<<16 | 41
+	queueUnbind        = 50<<16 | 50
+	queueUnbindOk      = 50<<16 | 51
+	basicQos           = 60<<16 | 10
+	basicQosOk         = 60<<16 | 11
+	basicConsume       = 60<<16 | 20
+	basicConsumeOk     = 60<<16 | 21
+	basicCancel        = 60<<16 | 30
+	basicCancelOk      = 60<<16 | 31
+	basicPublish       = 60<<16 | 40
+	basicReturn        = 60<<16 | 50
+	basicDeliver       = 60<<16 | 60
+	basicGet           = 60<<16 | 70
+	basicGetOk         = 60<<16 | 71
+	basicGetEmpty      = 60<<16 | 72
+	basicAck           = 60<<16 | 80
+	basicReject        = 60<<16 | 90
+	basicRecoverAsync  = 60<<16 | 100
+	basicRecover       = 60<<16 | 110
+	basicRecoverOk     = 60<<16 | 111
+	basicNack          = 60<<16 | 120
+	confirmSelect      = 85<<16 | 10
+	confirmSelectOk    = 85<<16 | 11
+	classBasic         = 60
+	protocolHeader     = "AMQP\x00\x00\x09\x01"
+	flagContentType    = 0x8000
+	flagContentEncode  = 0x4000
+	flagHeaders        = 0x2000
+	flagDeliveryMode   = 0x1000
+	flagPriority       = 0x0800
+	flagCorrelationID  = 0x0400
+	flagReplyTo        = 0x0200
+	flagExpiration     = 0x0100
+	flagMessageID      = 0x0080
+	flagTimestamp      = 0x0040
+	flagType           = 0x0020
+	flagUserID         = 0x0010
+	flagAppID          = 0x0008
+	flagContinuation   = 0x0001
+	replySuccess       = 200
+	noRoute            = 312
+	connectionForced   = 320
+	accessRefused      = 403
+	notFound           = 404
+	resourceLocked     = 405
+	preconditionFailed = 406
+	frameError         = 501
+	commandInvalid     = 503
+	channelError       = 504
+	unexpectedFrame    = 505
+	notAllowed         = 530
+	notImplemented     = 540
+)
+
+type frame struct {
+	typ     byte
+	channel uint16
+	payload []byte
+}
+
+func readFrame(r *bufio.Reader) (f frame, err error) {
+	var h [7]byte
+	_, err = io.ReadFull(r, h[:])
+	if err != nil {
+		return
+	}
+	f.typ = h[0]
+	f.channel = binary.BigEndian.Uint16(h[1:3])
+	size := binary.BigEndian.Uint32(h[3:7])
+	if size > frameMax {
+		err = errors.Errorf("frame size %d exceeds %d", size, frameMax)
+		return
+	}
+	f.payload = make([]byte, size+1)
+	_, err = io.ReadFull(r, f.payload)
+	if err != nil {
+		return
+	}
+	if f.payload[size] != frameEnd {
+		err = errors.Errorf("invalid frame end: %x", f.payload[size])
+		return
+	}
+	f.payload = f.payload[:size]
+	return
+}
+
+func appendFrame(b []byte, typ byte, channel uint16, payload []byte) []byte {
+	var h [7]byte
+	h[0] = typ
+	binary.BigEndian.PutUint16(h[1:3], channel)
+	binary.BigEndian.PutUint32(h[3:7], uint32(len(payload)))
+	b = append(b, h[:]...)
+	b = append(b, payload...)
+	return append(b, frameEnd)
+}
+
+// decoder reads method arguments, the first error is kept and later reads return zero values
+type decoder struct {
+	b   []byte
+	err error
+}
+
+func (d *decoder) next(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n < 0 || len(d.b) < n {
+		d.err = errors.Errorf("short payload")
+		return nil
+	}
+	rp := d.b[:n]
+	d.b = d.b[n:]
+	return rp
+}
+
+func (d *decoder) octet() byte {
+	if b := d.next(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (d *decoder) short() uint16 {
+	if b := d.next(2); b != nil {
+		return binary.BigEndian.Uint16(b)
+	}
+	return 0
+}
+
+func (d *decoder) long() uint32 {
+	if b := d.next(4); b != nil {
+		return binary.BigEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (d *decoder) longlong() uint64 {
+	if b := d.next(8); b != nil {
+		return binary.BigEndian.Uint64(b)
+	}
+	return 0
+}
+
+func (d *decoder) shortstr() string {
+	return string(d.next(int(d.octet())))
+}
+
+func (d *decoder) longstr() string {
+	return string(d.next(int(d.long())))
+}
+
+func (d *decoder) table() amqp.Table {
+	nested := &decoder{b: d.next(int(d.long()))}
+	if d.err != nil {
+		return nil
+	}
+	t := make(amqp.Table)
+	for len(nested.b) > 0 && nested.err == nil {
+		k := nested.shortstr()
+		t[k] = nested.field()
+	}
+	if nested.err != nil {
+		d.err = nested.err
+	}
+	return t
+}
+
+// field decodes a value to the same go type as streadway/amqp does
+func (d *decoder) field() interface{} {
+	switch d.octet() {
+	case 't':
+		return d.octet() != 0
+	case 'b':
+		return d.octet()
+	case 's':
+		return int16(d.short())
+	case 'I':
+		return int32(d.long())
+	case 'l':
+		return int64(d.longlong())
+	case 'f':
+		return math.Float32frombits(d.long())
+	case 'd':
+		return math.Float64frombits(d.longlong())
+	case 'D':
+		scale := d.octet()
+		return amqp.Decimal{Scale: scale, Value: int32(d.long())}
+	case 'S':
+		return d.longstr()
+	case 'A':
+		nested := &decoder{b: d.next(int(d.long()))}
+		arr := make([]interface{}, 0)
+		for len(nested.b) > 0 && nested.err == nil {
+			arr = append(arr, nested.field())
+		}
+		if nested.err != nil && d.err == nil {
+			d.err = nested.err
+		}
+		return arr
+	case 'T':
+		return time.Unix(int64(d.longlong()), 0)
+	case 'F':
+		return d.table()
+	case 'x':
+		return append([]byte(nil), d.next(int(d.long()))...)
+	case 'V':
+		return nil
+	}
+	if d.err == nil {
+		d.err = errors.Errorf("invalid field type")
+	}
+	return nil
+}
+
+func (d *decoder) properties() (p amqp.Publishing) {
+	flags := d.short()
+	// ignore continuation flags, no property is defined in them
+	for f := flags; f&flagContinuation != 0 && d.err == nil; {
+		f = d.short()
+	}
+	if flags&flagContentType != 0 {
+		p.ContentType = d.shortstr()
+	}
+	if flags&flagContentEncode != 0 {
+		p.ContentEncoding = d.shortstr()
+	}
+	if flags&flagHeaders != 0 {
+		p.Headers = d.table()
+	}
+	if flags&flagDeliveryMode != 0 {
+		p.DeliveryMode = d.octet()
+	}
+	if flags&flagPriority != 0 {
+		p.Priority = d.octet()
+	}
+	if flags&flagCorrelationID != 0 {
+		p.CorrelationId = d.shortstr()
+	}
+	if flags&flagReplyTo != 0 {
+		p.ReplyTo = d.shortstr()
+	}
+	if flags&flagExpiration != 0 {
+		p.Expiration = d.shortstr()
+	}
+	if flags&flagMessageID != 0 {
+		p.MessageId = d.shortstr()
+	}
+	if flags&flagTimestamp != 0 {
+		p.Timestamp = time.Unix(int64(d.longlong()), 0)
+	}
+	if flags&flagType != 0 {
+		p.Type = d.shortstr()
+	}
+	if flags&flagUserID != 0 {
+		p.UserId = d.shortstr()
+	}
+	if flags&flagAppID != 0 {
+		p.AppId = d.shortstr()
+	}
+	return
+}
+
+// encoder writes method arguments
+type encoder struct {
+	bytes.Buffer
+}
+
+func newMethod(id uint32) *encoder {
+	e := &encoder{}
+	e.long(id)
+	return e
+}
+
+func (e *encoder) octet(v byte) *encoder {
+	e.WriteByte(v)
+	return e
+}
+
+func (e *encoder) bits(v ...bool) *encoder {
+	var b byte
+	for i, item := range v {
+		if item {
+			b |= 1 << uint(i)
+		}
+	}
+	return e.octet(b)
+}
+
+func (e *encoder) short(v uint16) *encoder {
+	var b [2]byte
+	binary.BigEndian.PutUint16(b[:], v)
+	e.Write(b[:])
+	return e
+}
+
+func (e *encoder) long(v uint32) *encoder {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], v)
+	e.Write(b[:])
+	return e
+}
+
+func (e *encoder) longlong(v uint64) *encoder {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], v)
+	e.Write(b[:])
+	return e
+}
+
+func (e *encoder) shortstr(v string) *encoder {
+	if len(v) > math.MaxUint8 {
+		v = v[:math.MaxUint8]
+	}
+	e.octet(byte(len(v)))
+	e.WriteString(v)
+	return e
+}
+
+func (e *encoder) longstr(v string) *encoder {
+	e.long(uint32(len(v)))
+	e.WriteString(v)
+	return e
+}
+
+func (e *encoder) table(t amqp.Table) *encoder {
+	var nested encoder
+	for k, v := range t {
+		nested.shortstr(k)
+		nested.field(v)
+	}
+	e.long(uint32(nested.Len()))
+	e.Write(nested.Bytes())
+	return e
+}
+
+// field encodes a value like streadway/amqp does, unsupported types are written as void
+func (e *encoder) field(v interface{}) {
+	switch v := v.(type) {
+	case bool:
+		e.octet('t')
+		if v {
+			e.octet(1)
+		} else {
+			e.octet(0)
+		}
+	case byte:
+		e.octet('b').octet(v)
+	case int16:
+		e.octet('s').short(uint16(v))
+	case int:
+		e.octet('I').long(uint32(v))
+	case int32:
+		e.octet('I').long(uint32(v))
+	case int64:
+		e.octet('l').longlong(uint64(v))
+	case float32:
+		e.octet('f').long(math.Float32bits(v))
+	case float64:
+		e.octet('d').longlong(math.Float64bits(v))
+	case amqp.Decimal:
+		e.octet('D').octet(v.Scale).long(uint32(v.Value))
+	case string:
+		e.octet('S').longstr(v)
+	case []interface{}:
+		var nested encoder
+		for _, item := range v {
+			nested.field(item)
+		}
+		e.octet('A').long(uint32(nested.Len()))
+		e.Write(nested.Bytes())
+	case time.Time:
+		e.octet('T').longlong(uint64(v.Unix()))
+	case amqp.Table:
+		e.octet('F').table(v)
+	case []byte:
+		e.octet('x').long(uint32(len(v)))
+		e.Write(v)
+	default:
+		e.octet('V')
+	}
+}
+
+func (e *encoder) properties(p amqp.Publishing) *encoder {
+	var flags uint16
+	var props encoder
+	if p.ContentType != "" {
+		flags |= flagContentType
+		props.shortstr(p.ContentType)
+	}
+	if p.ContentEncoding != "" {
+		flags |= flagContentEncode
+		props.shortstr(p.ContentEncoding)
+	}
+	if len(p.Headers) > 0 {
+		flags |= flagHeaders
+		props.table(p.Headers)
+	}
+	if p.DeliveryMode > 0 {
+		flags |= flagDeliveryMode
+		props.octet(p.DeliveryMode)
+	}
+	if p.Priority > 0 {
+		flags |= flagPriority
+		props.octet(p.Priority)
+	}
+	if p.CorrelationId != "" {
+		flags |= flagCorrelationID
+		props.shortstr(p.CorrelationId)
+	}
+	if p.ReplyTo != "" {
+		flags |= flagReplyTo
+		props.shortstr(p.ReplyTo)
+	}
+	if p.Expiration != "" {
+		flags |= flagExpiration
+		props.shortstr(p.Expiration)
+	}
+	if p.MessageId != "" {
+		flags |= flagMessageID
+		props.shortstr(p.MessageId)
+	}
+	if !p.Timestamp.IsZero() {
+		flags |= flagTimestamp
+		props.longlong(uint64(p.Timestamp.Unix()))
+	}
+	if p.Type != "" {
+		flags |= flagType
+		props.shortstr(p.Type)
+	}
+	if p.UserId != "" {
+		flags |= flagUserID
+		props.shortstr(p.UserId)
+	}
+	if p.AppId != "" {
+		flags |= flagAppID
+		props.shortstr(p.AppId)
+	}
+	e.short(flags)
+	e.Write(props.Bytes())
+	return e
+}
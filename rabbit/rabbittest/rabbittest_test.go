@@ -0,0 +1,296 @@
+package rabbittest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-cinch/common/rabbit"
+	"github.com/streadway/amqp"
+)
+
+func newChannel(t *testing.T) (s *Server, ch *amqp.Channel) {
+	s, err := Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := amqp.Dial(s.URI())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch, err = conn.Channel()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = conn.Close()
+		s.Close()
+	})
+	return
+}
+
+func declare(t *testing.T, ch *amqp.Channel, ex, kind string, bindings map[string][]string, args ...amqp.Table) {
+	err := ch.ExchangeDeclare(ex, kind, true, false, false, false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for q, keys := range bindings {
+		_, err = ch.QueueDeclare(q, true, false, false, false, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, key := range keys {
+			var a amqp.Table
+			if i < len(args) {
+				a = args[i]
+			}
+			err = ch.QueueBind(q, key, ex, false, a)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
+
+func publish(t *testing.T, ch *amqp.Channel, ex, key string, msg amqp.Publishing) {
+	err := ch.Publish(ex, key, false, false, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestServer_route(t *testing.T) {
+	s, ch := newChannel(t)
+	declare(t, ch, "direct", amqp.ExchangeDirect, map[string][]string{"d1": {"k1"}, "d2": {"k2"}})
+	declare(t, ch, "fanout", amqp.ExchangeFanout, map[string][]string{"f1": {""}, "f2": {""}})
+	declare(t, ch, "topic", amqp.ExchangeTopic, map[string][]string{"t1": {"order.*"}, "t2": {"order.#"}, "t3": {"#.paid"}})
+	declare(t, ch, "headers", amqp.ExchangeHeaders, map[string][]string{"h1": {""}, "h2": {""}},
+		amqp.Table{"x-match": "all", "type": "order", "paid": true},
+	)
+	err := ch.QueueBind("h2", "", "headers", false, amqp.Table{"x-match": "any", "type": "order", "other": "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	publish(t, ch, "direct", "k1", amqp.Publishing{Body: []byte("1")})
+	publish(t, ch, "fanout", "any", amqp.Publishing{Body: []byte("2")})
+	publish(t, ch, "topic", "order.created", amqp.Publishing{Body: []byte("3")})
+	publish(t, ch, "topic", "order.item.paid", amqp.Publishing{Body: []byte("4")})
+	publish(t, ch, "headers", "", amqp.Publishing{Headers: amqp.Table{"type": "order"}, Body: []byte("5")})
+	publish(t, ch, "", "d2", amqp.Publishing{Body: []byte("6")})
+	// wait for async publish
+	_, _ = ch.QueueDeclarePassive("d1", true, false, false, false, nil)
+
+	for q, expect := range map[string]int{"d1": 1, "d2": 1, "f1": 1, "f2": 1, "t1": 1, "t2": 2, "t3": 1, "h1": 0, "h2": 1} {
+		if n := s.QueueLen(q); n != expect {
+			t.Errorf("queue %s expect %d messages, got %d", q, expect, n)
+		}
+	}
+	d, ok, err := ch.Get("t2", false)
+	if err != nil || !ok || string(d.Body) != "3" || d.RoutingKey != "order.created" {
+		t.Errorf("expect first message of t2, got %v %v %s", ok, err, d.Body)
+		return
+	}
+	if err = d.Ack(false); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestServer_deadLetter(t *testing.T) {
+	s, ch := newChannel(t)
+	declare(t, ch, "dlx", amqp.ExchangeDirect, map[string][]string{"dlq": {"dead"}})
+	_, err := ch.QueueDeclare("q1", true, false, false, false, amqp.Table{
+		"x-dead-letter-exchange":    "dlx",
+		"x-dead-letter-routing-key": "dead",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = ch.QueueDeclare("ttl", true, false, false, false, amqp.Table{
+		"x-message-ttl":             int32(50),
+		"x-dead-letter-exchange":    "",
+		"x-dead-letter-routing-key": "q1",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	publish(t, ch, "", "ttl", amqp.Publishing{Body: []byte("delay")})
+	var d amqp.Delivery
+	var ok bool
+	for i := 0; i < 50 && !ok; i++ {
+		time.Sleep(10 * time.Millisecond)
+		d, ok, err = ch.Get("q1", false)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !ok {
+		t.Errorf("expect message expired from ttl to q1")
+		return
+	}
+	if err = d.Reject(false); err != nil {
+		t.Fatal(err)
+	}
+	d, ok, err = ch.Get("dlq", true)
+	if err != nil || !ok {
+		t.Errorf("expect rejected message in dlq, got %v %v", ok, err)
+		return
+	}
+	deaths, _ := d.Headers["x-death"].([]interface{})
+	if len(deaths) != 2 || d.Headers["x-first-death-reason"] != "expired" {
+		t.Errorf("expect 2 x-death entries and first reason expired, got %v", d.Headers)
+		return
+	}
+	if last, _ := deaths[0].(amqp.Table); last["reason"] != "rejected" || last["queue"] != "q1" {
+		t.Errorf("expect last death rejected by q1, got %v", last)
+	}
+	if s.QueueLen("dlq") != 0 {
+		t.Errorf("expect dlq is empty")
+	}
+}
+
+func TestServer_consume(t *testing.T) {
+	_, ch := newChannel(t)
+	declare(t, ch, "ex", amqp.ExchangeDirect, map[string][]string{"q": {"k"}})
+	err := ch.Qos(1, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ds, err := ch.Consume("q", "c1", false, false, false, false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publish(t, ch, "ex", "k", amqp.Publishing{Body: []byte("1")})
+	publish(t, ch, "ex", "k", amqp.Publishing{Body: []byte("2")})
+
+	d := <-ds
+	if string(d.Body) != "1" || d.Redelivered {
+		t.Errorf("expect message 1, got %s", d.Body)
+		return
+	}
+	select {
+	case d2 := <-ds:
+		t.Errorf("expect prefetch 1, got %s", d2.Body)
+		return
+	case <-time.After(50 * time.Millisecond):
+	}
+	// requeue then it's delivered again before message 2
+	if err = d.Nack(false, true); err != nil {
+		t.Fatal(err)
+	}
+	d = <-ds
+	if string(d.Body) != "1" || !d.Redelivered {
+		t.Errorf("expect redelivered message 1, got %s %v", d.Body, d.Redelivered)
+		return
+	}
+	if err = d.Ack(false); err != nil {
+		t.Fatal(err)
+	}
+	d = <-ds
+	if string(d.Body) != "2" {
+		t.Errorf("expect message 2, got %s", d.Body)
+		return
+	}
+	if err = d.Ack(false); err != nil {
+		t.Fatal(err)
+	}
+	if err = ch.Cancel("c1", false); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestServer_confirm(t *testing.T) {
+	_, ch := newChannel(t)
+	declare(t, ch, "ex", amqp.ExchangeDirect, map[string][]string{"q": {"k"}})
+	err := ch.Confirm(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	confirms := ch.NotifyPublish(make(chan amqp.Confirmation, 2))
+	returns := ch.NotifyReturn(make(chan amqp.Return, 1))
+
+	err = ch.Publish("ex", "none", true, false, amqp.Publishing{Body: []byte("1")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := <-returns
+	if r.ReplyCode != amqp.NoRoute || string(r.Body) != "1" {
+		t.Errorf("expect no route return, got %d", r.ReplyCode)
+		return
+	}
+	if c := <-confirms; !c.Ack || c.DeliveryTag != 1 {
+		t.Errorf("expect ack 1, got %+v", c)
+		return
+	}
+	publish(t, ch, "ex", "k", amqp.Publishing{Body: []byte("2")})
+	if c := <-confirms; !c.Ack || c.DeliveryTag != 2 {
+		t.Errorf("expect ack 2, got %+v", c)
+	}
+}
+
+func TestServer_inequivalent(t *testing.T) {
+	_, ch := newChannel(t)
+	_, err := ch.QueueDeclare("q", true, false, false, false, amqp.Table{"x-message-ttl": int32(1000)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// integer types are compared by value
+	_, err = ch.QueueDeclare("q", true, false, false, false, amqp.Table{"x-message-ttl": int64(1000)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = ch.QueueDeclare("q", true, false, false, false, amqp.Table{"x-message-ttl": int32(2000)})
+	e, ok := err.(*amqp.Error)
+	if !ok || e.Code != amqp.PreconditionFailed {
+		t.Errorf("expect precondition failed, got %v", err)
+	}
+	_, err = ch.QueueDeclarePassive("missing", true, false, false, false, nil)
+	if err == nil {
+		t.Errorf("expect channel closed")
+	}
+}
+
+func TestServer_rabbit(t *testing.T) {
+	s, err := Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	rb := rabbit.New(s.URI(), rabbit.WithMaxConnection(1), rabbit.WithMaxChannel(2))
+	if rb.Error != nil {
+		t.Fatal(rb.Error)
+	}
+	defer rb.Close()
+	ex := rb.Exchange(rabbit.WithExchangeName("ex"))
+	qu := ex.Queue(rabbit.WithQueueName("q"), rabbit.WithQueueRouteKeys("k"))
+	if qu.Error != nil {
+		t.Fatal(qu.Error)
+	}
+
+	received := make(chan string, 1)
+	co, err := qu.Consume(func(ctx context.Context, q string, d amqp.Delivery) bool {
+		received <- string(d.Body)
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ex.PublishByte([]byte("hello"), rabbit.WithPublishRouteKey("k"), rabbit.WithPublishConfirm(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case body := <-received:
+		if body != "hello" {
+			t.Errorf("expect hello, got %s", body)
+		}
+	case <-time.After(3 * time.Second):
+		t.Errorf("expect message consumed")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	if err = co.Stop(ctx); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,161 @@
+package rabbit
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/go-cinch/common/log"
+	"github.com/pkg/errors"
+	"github.com/streadway/amqp"
+)
+
+const retryCountHeader = "x-retry-count"
+
+var errRetryExceeded = errors.New("maximum retry exceeded")
+
+// QueueWithRetry bind a queue with delay retry queues and a parking lot queue.
+// nacked delivery is moved to the delay queue of its retry count,
+// then dead-lettered back to this queue after ttl, once all delays used it is moved to parking lot queue
+func (ex *Exchange) QueueWithRetry(options ...func(*QueueOptions)) *Queue {
+	qu := ex.Queue(options...)
+	if qu.Error != nil {
+		return qu
+	}
+	if len(qu.ops.retryDelays) == 0 {
+		qu.Error = errors.Errorf("retry delays is empty")
+		return qu
+	}
+	if qu.ops.declare {
+		qu.declareRetry()
+	}
+	return qu
+}
+
+// RetryQueue delay queue name of retry count, count starts from 0 and must be less than len(delays)
+func (qu *Queue) RetryQueue(count int) string {
+	return strings.Join([]string{qu.ops.name, "retry", strconv.Itoa(int(qu.ops.retryDelays[count]))}, ".")
+}
+
+// ParkingQueue parking lot queue name
+func (qu *Queue) ParkingQueue() string {
+	return strings.Join([]string{qu.ops.name, "parking"}, ".")
+}
+
+// declare delay queues and parking lot queue, they are bound to default exchange
+func (qu *Queue) declareRetry() {
+	def := qu.ex.rb.defaultExchange()
+	for i, delay := range qu.ops.retryDelays {
+		q := def.Queue(
+			WithQueueName(qu.RetryQueue(i)),
+			WithQueueBind(false),
+			WithQueueMessageTTL(delay),
+			WithQueueArgs(amqp.Table{
+				"x-dead-letter-exchange":    "",
+				"x-dead-letter-routing-key": qu.ops.name,
+			}),
+		)
+		if q.Error != nil {
+			qu.Error = q.Error
+			return
+		}
+	}
+	q := def.Queue(
+		WithQueueName(qu.ParkingQueue()),
+		WithQueueBind(false),
+	)
+	if q.Error != nil {
+		qu.Error = q.Error
+	}
+}
+
+// defaultExchange the amqp default exchange, route key is the target queue name
+func (rb *Rabbit) defaultExchange() *Exchange {
+	return &Exchange{
+		ops: ExchangeOptions{
+			kind:    amqp.ExchangeDirect,
+			durable: true,
+		},
+		rb: rb,
+	}
+}
+
+func (co *Consumer) retryable() bool {
+	return len(co.qu.ops.retryDelays) > 0 || co.ops.nackRetry
+}
+
+// retry republish nacked delivery with retry count increased, return nil means original delivery can be acked
+func (co *Consumer) retry(ctx context.Context, d amqp.Delivery) (err error) {
+	count := retryCount(d.Headers)
+	headers := make(amqp.Table, len(d.Headers)+1)
+	for k, v := range d.Headers {
+		headers[k] = v
+	}
+	headers[retryCountHeader] = count + 1
+	options := []func(*PublishOptions){
+		WithPublishCtx(ctx),
+		WithPublishHeaders(headers),
+		// returned if retry queues are not declared, the delivery will be nacked instead of lost
+		WithPublishMandatory(true),
+		WithPublishConfirm(true),
+		// keep msg id, type and other properties, so consumers can still dedupe and dispatch by type
+		WithPublishMessageID(d.MessageId),
+		WithPublishType(d.Type),
+		withPublishOrigin(d),
+	}
+	if d.ContentType != "" {
+		options = append(options, WithPublishContentType(d.ContentType))
+	}
+	delays := co.qu.ops.retryDelays
+	if len(delays) == 0 {
+		// no retry queues, republish to origin exchange immediately
+		if count+1 >= co.ops.nackMaxRetryCount {
+			log.WithContext(ctx).Warn("maximum retry %d exceeded, discard data", co.ops.nackMaxRetryCount)
+			err = errors.WithStack(errRetryExceeded)
+			return
+		}
+		err = co.qu.ex.PublishByte(d.Body, append(options, WithPublishRouteKey(d.RoutingKey))...)
+		return
+	}
+	key := co.qu.ParkingQueue()
+	if int(count) < len(delays) {
+		key = co.qu.RetryQueue(int(count))
+	} else {
+		log.WithContext(ctx).Warn("maximum retry %d exceeded, move to %s", len(delays), key)
+	}
+	err = co.qu.ex.rb.defaultExchange().PublishByte(d.Body, append(options, WithPublishRouteKey(key))...)
+	return
+}
+
+// RetryBackoff exponential delays(milli second), initial*factor^0, initial*factor^1...
+func RetryBackoff(initial int32, factor float64, count int) []int32 {
+	delays := make([]int32, 0, count)
+	for i := 0; i < count; i++ {
+		delay := float64(initial) * math.Pow(factor, float64(i))
+		if delay > math.MaxInt32 {
+			delay = math.MaxInt32
+		}
+		delays = append(delays, int32(delay))
+	}
+	return delays
+}
+
+func retryCount(headers amqp.Table) int32 {
+	switch v := headers[retryCountHeader].(type) {
+	case int32:
+		return v
+	case int64:
+		return int32(v)
+	case int:
+		return int32(v)
+	case int16:
+		return int32(v)
+	case int8:
+		return int32(v)
+	case float64:
+		return int32(v)
+	default:
+		return 0
+	}
+}
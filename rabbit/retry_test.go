@@ -0,0 +1,179 @@
+package rabbit
+
+import (
+	"context"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-cinch/common/rabbit/rabbittest"
+	"github.com/streadway/amqp"
+)
+
+func TestRetryBackoff(t *testing.T) {
+	delays := RetryBackoff(1000, 2, 5)
+	expect := []int32{1000, 2000, 4000, 8000, 16000}
+	if !reflect.DeepEqual(delays, expect) {
+		t.Errorf("expect %v, got %v", expect, delays)
+	}
+}
+
+func TestQueue_RetryQueue(t *testing.T) {
+	ops := getQueueOptionsOrSetDefault(nil)
+	WithQueueName("q1")(ops)
+	WithQueueRetryDelays(1000, 0, 5000)(ops)
+	qu := &Queue{ops: *ops}
+	if len(qu.ops.retryDelays) != 2 {
+		t.Errorf("expect 2 delays, got %v", qu.ops.retryDelays)
+		return
+	}
+	if v := qu.RetryQueue(1); v != "q1.retry.5000" {
+		t.Errorf("expect q1.retry.5000, got %s", v)
+	}
+	if v := qu.ParkingQueue(); v != "q1.parking" {
+		t.Errorf("expect q1.parking, got %s", v)
+	}
+}
+
+func TestRetryCount(t *testing.T) {
+	for _, item := range []interface{}{int32(3), int64(3), float64(3)} {
+		if v := retryCount(amqp.Table{retryCountHeader: item}); v != 3 {
+			t.Errorf("expect 3, got %d", v)
+		}
+	}
+	if v := retryCount(nil); v != 0 {
+		t.Errorf("expect 0, got %d", v)
+	}
+}
+
+// consumeFailed publish a msg to ex1/rt1, consume it with handler always returns false,
+// stop after wait, returns handler call count
+func consumeFailed(t *testing.T, s *rabbittest.Server, qu *Queue, wait time.Duration, options ...func(*ConsumeOptions)) int32 {
+	err := qu.ex.PublishByte([]byte("{}"), WithPublishRouteKey("rt1"), WithPublishConfirm(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var calls int32
+	co, err := qu.Consume(func(context.Context, string, amqp.Delivery) bool {
+		atomic.AddInt32(&calls, 1)
+		return false
+	}, options...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(wait)
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	if err = co.Stop(ctx); err != nil {
+		t.Fatal(err)
+	}
+	return atomic.LoadInt32(&calls)
+}
+
+func TestConsumer_retry_notDeclared(t *testing.T) {
+	s, err := rabbittest.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	rb := New(s.URI())
+	defer rb.Close()
+	// retry queues are not declared without QueueWithRetry
+	qu := rb.Exchange(WithExchangeName("ex1")).Queue(
+		WithQueueName("q1"),
+		WithQueueRouteKeys("rt1"),
+		WithQueueRetryDelays(1000),
+	)
+	if qu.Error != nil {
+		t.Fatal(qu.Error)
+	}
+	// retry publish is returned, so delivery is nacked and requeued instead of acked as retried
+	calls := consumeFailed(t, s, qu, 300*time.Millisecond, WithConsumeNackRequeue(true))
+	if calls < 2 {
+		t.Errorf("expect redelivered, got %d", calls)
+	}
+}
+
+func TestConsumer_retry_exceededNotRequeued(t *testing.T) {
+	s, err := rabbittest.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	rb := New(s.URI())
+	defer rb.Close()
+	qu := rb.Exchange(WithExchangeName("ex1")).Queue(WithQueueName("q1"), WithQueueRouteKeys("rt1"))
+	if qu.Error != nil {
+		t.Fatal(qu.Error)
+	}
+	calls := consumeFailed(
+		t, s, qu, 500*time.Millisecond,
+		WithConsumeNackRetry(true),
+		WithConsumeNackMaxRetryCount(2),
+		WithConsumeNackRequeue(true),
+	)
+	if calls != 2 {
+		t.Errorf("expect handled twice then discarded, got %d", calls)
+	}
+	if n := s.QueueLen("q1"); n != 0 {
+		t.Errorf("expect queue is empty, got %d", n)
+	}
+}
+
+func TestConsumer_retry_keepProperties(t *testing.T) {
+	s, err := rabbittest.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	rb := New(s.URI())
+	defer rb.Close()
+	qu := rb.Exchange(WithExchangeName("ex1")).Queue(WithQueueName("q1"), WithQueueRouteKeys("rt1"))
+	if qu.Error != nil {
+		t.Fatal(qu.Error)
+	}
+	err = qu.ex.PublishByte(
+		[]byte("{}"),
+		WithPublishRouteKey("rt1"),
+		WithPublishConfirm(true),
+		WithPublishMessageID("order-1"),
+		WithPublishType("order.created"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deliveries := make(chan amqp.Delivery, 2)
+	var calls int32
+	co, err := qu.Consume(func(_ context.Context, _ string, d amqp.Delivery) bool {
+		// fail the first delivery only
+		n := atomic.AddInt32(&calls, 1)
+		if n <= 2 {
+			deliveries <- d
+		}
+		return n > 1
+	}, WithConsumeNackRetry(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer co.Stop(context.Background())
+	var got []amqp.Delivery
+	for len(got) < 2 {
+		select {
+		case d := <-deliveries:
+			got = append(got, d)
+		case <-time.After(3 * time.Second):
+			t.Fatalf("expect retried delivery, got %d", len(got))
+		}
+	}
+	first, retried := got[0], got[1]
+	if retried.MessageId != "order-1" || retried.Type != "order.created" {
+		t.Errorf("expect order-1/order.created, got %s/%s", retried.MessageId, retried.Type)
+	}
+	if !retried.Timestamp.Equal(first.Timestamp) || retried.AppId != first.AppId {
+		t.Errorf("expect properties %v/%s, got %v/%s", first.Timestamp, first.AppId, retried.Timestamp, retried.AppId)
+	}
+	if v := retryCount(retried.Headers); v != 1 {
+		t.Errorf("expect retry count 1, got %d", v)
+	}
+}
@@ -0,0 +1,203 @@
+package rabbit
+
+import (
+	"context"
+	"net/url"
+	"sync"
+
+	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/transport"
+	"github.com/pkg/errors"
+	"github.com/streadway/amqp"
+)
+
+var (
+	_ transport.Server      = (*Server)(nil)
+	_ transport.Transporter = (*Transport)(nil)
+)
+
+const KindAMQP transport.Kind = "amqp"
+
+// ErrHandlerNack handler returns false, delivery will be nacked
+var ErrHandlerNack = errors.New("handler nack")
+
+// Message is the request passed through middlewares, String returns body so logging middleware can print it
+type Message struct {
+	Queue    string
+	Delivery amqp.Delivery
+}
+
+func (m *Message) String() string {
+	return string(m.Delivery.Body)
+}
+
+// Transport is the amqp transport of a delivery, operation is queue name
+type Transport struct {
+	endpoint    string
+	operation   string
+	reqHeader   headerCarrier
+	replyHeader headerCarrier
+}
+
+func (tr *Transport) Kind() transport.Kind {
+	return KindAMQP
+}
+
+func (tr *Transport) Endpoint() string {
+	return tr.endpoint
+}
+
+func (tr *Transport) Operation() string {
+	return tr.operation
+}
+
+// RequestHeader amqp headers of the delivery
+func (tr *Transport) RequestHeader() transport.Header {
+	return tr.reqHeader
+}
+
+// ReplyHeader amqp has no reply, it's only for middlewares which write reply headers
+func (tr *Transport) ReplyHeader() transport.Header {
+	return tr.replyHeader
+}
+
+type subscriber struct {
+	qu      *Queue
+	handler func(context.Context, string, amqp.Delivery) bool
+	options []func(*ConsumeOptions)
+}
+
+// Server is a kratos transport.Server, all handlers start consuming on Start and stop gracefully on Stop
+type Server struct {
+	ops       ServerOptions
+	rb        *Rabbit
+	lock      sync.Mutex
+	subs      []subscriber
+	consumers []*Consumer
+	Error     error
+}
+
+func NewServer(rb *Rabbit, options ...func(*ServerOptions)) (srv *Server) {
+	ops := getServerOptionsOrSetDefault(nil)
+	for _, f := range options {
+		f(ops)
+	}
+	srv = &Server{
+		ops: *ops,
+		rb:  rb,
+	}
+	if rb == nil {
+		srv.Error = errors.Errorf("rabbit is nil")
+		return
+	}
+	if rb.Error != nil {
+		srv.Error = rb.Error
+		return
+	}
+	if srv.ops.endpoint == "" {
+		srv.ops.endpoint = endpoint(rb.poolConfig.URI)
+	}
+	return
+}
+
+// Handle register handler of queue, it should be called before Start
+func (srv *Server) Handle(qu *Queue, handler func(context.Context, string, amqp.Delivery) bool, options ...func(*ConsumeOptions)) *Server {
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+	if srv.Error != nil {
+		return srv
+	}
+	if qu == nil {
+		srv.Error = errors.Errorf("queue is nil")
+		return srv
+	}
+	if qu.Error != nil {
+		srv.Error = qu.Error
+		return srv
+	}
+	if handler == nil {
+		srv.Error = errors.Errorf("handler is nil")
+		return srv
+	}
+	srv.subs = append(srv.subs, subscriber{
+		qu:      qu,
+		handler: handler,
+		options: options,
+	})
+	return srv
+}
+
+// Start consume all registered queues in background
+func (srv *Server) Start(_ context.Context) (err error) {
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+	if srv.Error != nil {
+		err = errors.WithStack(srv.Error)
+		return
+	}
+	for _, item := range srv.subs {
+		var co *Consumer
+		co, err = item.qu.consume(srv.deliveryHandler(item.handler), item.options...)
+		if err != nil {
+			return
+		}
+		srv.consumers = append(srv.consumers, co)
+	}
+	return
+}
+
+// Stop stop all consumers and wait in-flight handlers finished or ctx done
+func (srv *Server) Stop(ctx context.Context) (err error) {
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+	for _, co := range srv.consumers {
+		e := co.Stop(ctx)
+		if e != nil && err == nil {
+			err = e
+		}
+	}
+	srv.consumers = nil
+	return
+}
+
+// deliveryHandler wrap handler with middlewares, handler returns false or any middleware returns error means nack
+func (srv *Server) deliveryHandler(handler func(context.Context, string, amqp.Delivery) bool) deliveryHandler {
+	next := func(ctx context.Context, req interface{}) (rp interface{}, err error) {
+		m := req.(*Message)
+		if !handler(ctx, m.Queue, m.Delivery) {
+			err = ErrHandlerNack
+		}
+		return
+	}
+	h := middleware.Chain(srv.ops.middlewares...)(next)
+	return func(ctx context.Context, q string, d amqp.Delivery) action {
+		headers := d.Headers
+		if headers == nil {
+			headers = make(amqp.Table)
+		}
+		ctx = transport.NewServerContext(ctx, &Transport{
+			endpoint:    srv.ops.endpoint,
+			operation:   q,
+			reqHeader:   headerCarrier(headers),
+			replyHeader: make(headerCarrier),
+		})
+		_, err := h(ctx, &Message{
+			Queue:    q,
+			Delivery: d,
+		})
+		if err != nil {
+			return actionNack
+		}
+		return actionAck
+	}
+}
+
+// endpoint remove credentials from dsn
+func endpoint(dsn string) string {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return ""
+	}
+	u.User = nil
+	return u.String()
+}
@@ -0,0 +1,50 @@
+package rabbit
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/transport"
+	"github.com/streadway/amqp"
+)
+
+func TestServer_deliveryHandler(t *testing.T) {
+	var tenant, operation string
+	m := func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			if tr, ok := transport.FromServerContext(ctx); ok {
+				tenant = tr.RequestHeader().Get("x-tenant-id")
+				operation = tr.Operation()
+			}
+			return handler(ctx, req)
+		}
+	}
+	srv := &Server{ops: ServerOptions{middlewares: []middleware.Middleware{m}}}
+	h := srv.deliveryHandler(func(ctx context.Context, q string, d amqp.Delivery) bool {
+		return string(d.Body) == "ok"
+	})
+
+	d := amqp.Delivery{
+		Headers: amqp.Table{"x-tenant-id": "t1"},
+		Body:    []byte("ok"),
+	}
+	if act := h(context.Background(), "q1", d); act != actionAck {
+		t.Errorf("expect ack, got %d", act)
+		return
+	}
+	if tenant != "t1" || operation != "q1" {
+		t.Errorf("expect tenant t1 and operation q1, got %s %s", tenant, operation)
+		return
+	}
+	d.Body = []byte("fail")
+	if act := h(context.Background(), "q1", d); act != actionNack {
+		t.Errorf("expect nack, got %d", act)
+	}
+}
+
+func Test_endpoint(t *testing.T) {
+	if e := endpoint(uri); e != "amqp://127.0.0.1:5672/" {
+		t.Errorf("expect credentials removed, got %s", e)
+	}
+}
@@ -0,0 +1,296 @@
+package rabbit
+
+import (
+	"context"
+	"math"
+
+	// register yml codec, so .yml file can be loaded by kratos config
+	_ "github.com/go-cinch/common/plugins/kratos/encoding/yml"
+
+	"github.com/go-cinch/common/log"
+	"github.com/go-kratos/kratos/v2/config"
+	"github.com/pkg/errors"
+	"github.com/streadway/amqp"
+)
+
+// Topology declarative exchanges, queues and bindings, json/yaml keys are lower camel case
+type Topology struct {
+	Exchanges []TopologyExchange `json:"exchanges" yaml:"exchanges"`
+	Queues    []TopologyQueue    `json:"queues" yaml:"queues"`
+	Bindings  []TopologyBinding  `json:"bindings" yaml:"bindings"`
+}
+
+type TopologyExchange struct {
+	Name string `json:"name" yaml:"name"`
+	// Kind direct/fanout/topic/headers, default direct
+	Kind string `json:"kind" yaml:"kind"`
+	// Durable default true
+	Durable    *bool                  `json:"durable" yaml:"durable"`
+	AutoDelete bool                   `json:"autoDelete" yaml:"autoDelete"`
+	Internal   bool                   `json:"internal" yaml:"internal"`
+	Args       map[string]interface{} `json:"args" yaml:"args"`
+}
+
+type TopologyQueue struct {
+	Name string `json:"name" yaml:"name"`
+	// Durable default true
+	Durable    *bool                  `json:"durable" yaml:"durable"`
+	AutoDelete bool                   `json:"autoDelete" yaml:"autoDelete"`
+	Exclusive  bool                   `json:"exclusive" yaml:"exclusive"`
+	Args       map[string]interface{} `json:"args" yaml:"args"`
+	// MessageTTL msg expiration(milli second), 0 means no ttl
+	MessageTTL int32 `json:"messageTTL" yaml:"messageTTL"`
+	// DeadLetterExchange dead letter exchange name
+	DeadLetterExchange string `json:"deadLetterExchange" yaml:"deadLetterExchange"`
+	// DeadLetterKey dead letter route key
+	DeadLetterKey string `json:"deadLetterKey" yaml:"deadLetterKey"`
+	// RetryDelays declare delay retry queues and parking lot queue like QueueWithRetry
+	RetryDelays []int32 `json:"retryDelays" yaml:"retryDelays"`
+}
+
+type TopologyBinding struct {
+	Exchange  string                 `json:"exchange" yaml:"exchange"`
+	Queue     string                 `json:"queue" yaml:"queue"`
+	RouteKeys []string               `json:"routeKeys" yaml:"routeKeys"`
+	Args      map[string]interface{} `json:"args" yaml:"args"`
+}
+
+// Drift an entity already exists on broker but is different from topology
+type Drift struct {
+	Kind   string
+	Name   string
+	Reason string
+}
+
+// LoadTopology load topology from kratos config sources, such as file.NewSource("topology.yml")
+func LoadTopology(sources ...config.Source) (t Topology, err error) {
+	c := config.New(config.WithSource(sources...))
+	defer c.Close()
+	err = c.Load()
+	if err != nil {
+		err = errors.WithStack(err)
+		return
+	}
+	err = c.Scan(&t)
+	if err != nil {
+		err = errors.WithStack(err)
+	}
+	return
+}
+
+// Apply declare everything of topology, it's idempotent.
+// exchanges/queues which exist with different properties/args are returned as drifts and not changed,
+// bindings are only added: amqp can not list bindings, so extra bindings on broker are not detected or removed.
+// ctx limits waiting for channels while broker is down
+func (rb *Rabbit) Apply(ctx context.Context, t Topology) (drifts []Drift, err error) {
+	if rb.Error != nil {
+		err = errors.WithStack(rb.Error)
+		return
+	}
+	for _, item := range t.Exchanges {
+		ex := rb.beforeExchange(item.options()...)
+		if ex.Error != nil {
+			err = errors.WithStack(ex.Error)
+			return
+		}
+		var drift *Drift
+		drift, err = rb.apply(
+			ctx,
+			"exchange",
+			ex.ops.name,
+			func(ch *amqp.Channel) error {
+				return ch.ExchangeDeclarePassive(ex.ops.name, ex.ops.kind, ex.ops.durable, ex.ops.autoDelete, ex.ops.internal, false, ex.ops.args)
+			},
+			func(ch *amqp.Channel) error {
+				return ch.ExchangeDeclare(ex.ops.name, ex.ops.kind, ex.ops.durable, ex.ops.autoDelete, ex.ops.internal, false, ex.ops.args)
+			},
+		)
+		if err != nil {
+			return
+		}
+		if drift != nil {
+			drifts = append(drifts, *drift)
+		}
+	}
+	def := rb.defaultExchange()
+	for _, item := range t.Queues {
+		for _, options := range item.options() {
+			qu := def.beforeQueue(options...)
+			if qu.Error != nil {
+				err = errors.WithStack(qu.Error)
+				return
+			}
+			var drift *Drift
+			drift, err = rb.apply(
+				ctx,
+				"queue",
+				qu.ops.name,
+				func(ch *amqp.Channel) (e error) {
+					_, e = ch.QueueDeclarePassive(qu.ops.name, qu.ops.durable, qu.ops.autoDelete, qu.ops.exclusive, false, qu.ops.args)
+					return
+				},
+				func(ch *amqp.Channel) (e error) {
+					_, e = ch.QueueDeclare(qu.ops.name, qu.ops.durable, qu.ops.autoDelete, qu.ops.exclusive, false, qu.ops.args)
+					return
+				},
+			)
+			if err != nil {
+				return
+			}
+			if drift != nil {
+				drifts = append(drifts, *drift)
+			}
+		}
+	}
+	for _, item := range t.Bindings {
+		if item.Exchange == "" || item.Queue == "" {
+			err = errors.Errorf("binding exchange or queue is empty")
+			return
+		}
+		keys := item.RouteKeys
+		if len(keys) == 0 {
+			// fanout/headers exchange ignores route key
+			keys = []string{""}
+		}
+		for _, key := range keys {
+			var ch *amqp.Channel
+			ch, err = rb.channel(ctx, nil, false)
+			if err != nil {
+				return
+			}
+			err = ch.QueueBind(item.Queue, key, item.Exchange, false, normalizeArgs(item.Args))
+			_ = ch.Close()
+			if err != nil {
+				err = errors.Wrapf(err, "bind queue %s to exchange %s with key %s failed", item.Queue, item.Exchange, key)
+				return
+			}
+		}
+	}
+	for _, item := range drifts {
+		log.
+			WithContext(rb.ops.ctx).
+			WithField("reason", item.Reason).
+			Warn("%s %s drifted from topology", item.Kind, item.Name)
+	}
+	return
+}
+
+// apply check entity exists by passive declare, then declare it,
+// a precondition failed error means the existing one is different
+func (rb *Rabbit) apply(ctx context.Context, kind, name string, passive, declare func(ch *amqp.Channel) error) (drift *Drift, err error) {
+	exist := true
+	// server closes channel on any declare error, so use a new channel each time
+	ch, err := rb.channel(ctx, nil, false)
+	if err != nil {
+		return
+	}
+	e := passive(ch)
+	_ = ch.Close()
+	if e != nil {
+		if !isAmqpCode(e, amqp.NotFound) {
+			err = errors.Wrapf(e, "check %s %s failed", kind, name)
+			return
+		}
+		exist = false
+	}
+	ch, err = rb.channel(ctx, nil, false)
+	if err != nil {
+		return
+	}
+	defer ch.Close()
+	e = declare(ch)
+	if e != nil {
+		if isAmqpCode(e, amqp.PreconditionFailed) {
+			drift = &Drift{
+				Kind:   kind,
+				Name:   name,
+				Reason: e.Error(),
+			}
+			return
+		}
+		err = errors.Wrapf(e, "declare %s %s failed", kind, name)
+		return
+	}
+	if !exist {
+		log.WithContext(rb.ops.ctx).Info("%s %s created", kind, name)
+	}
+	return
+}
+
+func (t TopologyExchange) options() []func(*ExchangeOptions) {
+	ops := []func(*ExchangeOptions){
+		WithExchangeName(t.Name),
+		WithExchangeAutoDelete(t.AutoDelete),
+		WithExchangeInternal(t.Internal),
+		WithExchangeArgs(normalizeArgs(t.Args)),
+	}
+	if t.Kind != "" {
+		ops = append(ops, WithExchangeKind(t.Kind))
+	}
+	if t.Durable != nil {
+		ops = append(ops, WithExchangeDurable(*t.Durable))
+	}
+	return ops
+}
+
+// options of the queue, and its retry queues if it has retry delays
+func (t TopologyQueue) options() [][]func(*QueueOptions) {
+	args := normalizeArgs(t.Args)
+	if t.DeadLetterExchange != "" {
+		args["x-dead-letter-exchange"] = t.DeadLetterExchange
+		if t.DeadLetterKey != "" {
+			args["x-dead-letter-routing-key"] = t.DeadLetterKey
+		}
+	}
+	ops := []func(*QueueOptions){
+		WithQueueName(t.Name),
+		WithQueueAutoDelete(t.AutoDelete),
+		WithQueueExclusive(t.Exclusive),
+		WithQueueArgs(args),
+		WithQueueMessageTTL(t.MessageTTL),
+		WithQueueRetryDelays(t.RetryDelays...),
+	}
+	if t.Durable != nil {
+		ops = append(ops, WithQueueDurable(*t.Durable))
+	}
+	rp := [][]func(*QueueOptions){ops}
+	if len(t.RetryDelays) == 0 {
+		return rp
+	}
+	qu := Queue{ops: *getQueueOptionsOrSetDefault(nil)}
+	for _, f := range ops {
+		f(&qu.ops)
+	}
+	for i, delay := range qu.ops.retryDelays {
+		rp = append(rp, []func(*QueueOptions){
+			WithQueueName(qu.RetryQueue(i)),
+			WithQueueMessageTTL(delay),
+			WithQueueArgs(amqp.Table{
+				"x-dead-letter-exchange":    "",
+				"x-dead-letter-routing-key": t.Name,
+			}),
+		})
+	}
+	rp = append(rp, []func(*QueueOptions){
+		WithQueueName(qu.ParkingQueue()),
+	})
+	return rp
+}
+
+// normalizeArgs json numbers are float64, but broker requires integer for args such as x-message-ttl
+func normalizeArgs(args map[string]interface{}) amqp.Table {
+	rp := make(amqp.Table, len(args))
+	for k, v := range args {
+		if f, ok := v.(float64); ok && f == math.Trunc(f) {
+			rp[k] = int64(f)
+			continue
+		}
+		rp[k] = v
+	}
+	return rp
+}
+
+func isAmqpCode(err error, code int) bool {
+	var e *amqp.Error
+	return errors.As(err, &e) && e.Code == code
+}
@@ -0,0 +1,125 @@
+package rabbit
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-cinch/common/rabbit/rabbittest"
+	"github.com/go-kratos/kratos/v2/config/file"
+)
+
+const topologyYml = `
+exchanges:
+  - name: ex1
+    kind: topic
+  - name: dl-ex
+queues:
+  - name: q1
+    messageTTL: 30000
+    deadLetterExchange: dl-ex
+    deadLetterKey: dlr
+    retryDelays: [1000, 5000]
+    args:
+      x-max-length: 1000
+  - name: dlq
+bindings:
+  - exchange: ex1
+    queue: q1
+    routeKeys: ["rt1", "rt2.#"]
+  - exchange: dl-ex
+    queue: dlq
+    routeKeys: ["dlr"]
+`
+
+func loadTestTopology(t *testing.T) Topology {
+	p := filepath.Join(t.TempDir(), "topology.yml")
+	err := os.WriteFile(p, []byte(topologyYml), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tp, err := LoadTopology(file.NewSource(p))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tp
+}
+
+func TestLoadTopology(t *testing.T) {
+	tp := loadTestTopology(t)
+	if len(tp.Exchanges) != 2 || len(tp.Queues) != 2 || len(tp.Bindings) != 2 {
+		t.Errorf("unexpected topology: %+v", tp)
+		return
+	}
+	q := tp.Queues[0]
+	if q.MessageTTL != 30000 || q.DeadLetterExchange != "dl-ex" || len(q.RetryDelays) != 2 {
+		t.Errorf("unexpected queue: %+v", q)
+		return
+	}
+
+	options := q.options()
+	// q1, 2 retry queues and parking lot queue
+	if len(options) != 4 {
+		t.Errorf("expect 4 queues, got %d", len(options))
+		return
+	}
+	qu := (&Exchange{}).beforeQueue(options[0]...)
+	if qu.Error != nil {
+		t.Error(qu.Error)
+		return
+	}
+	if v, ok := qu.ops.args["x-max-length"].(int64); !ok || v != 1000 {
+		t.Errorf("expect x-max-length int64 1000, got %T %v", qu.ops.args["x-max-length"], qu.ops.args["x-max-length"])
+	}
+	if v := qu.ops.args["x-dead-letter-routing-key"]; v != "dlr" {
+		t.Errorf("expect dead letter key dlr, got %v", v)
+	}
+	retry := (&Exchange{}).beforeQueue(options[2]...)
+	if retry.ops.name != "q1.retry.5000" || retry.ops.args["x-message-ttl"] != int32(5000) {
+		t.Errorf("unexpected retry queue: %s %v", retry.ops.name, retry.ops.args)
+	}
+}
+
+func TestRabbit_Apply(t *testing.T) {
+	rb := New(uri)
+	if rb.Error != nil {
+		panic(rb.Error)
+	}
+	drifts, err := rb.Apply(context.Background(), loadTestTopology(t))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	t.Log(drifts)
+}
+
+func TestRabbit_Apply_brokerDown(t *testing.T) {
+	s, err := rabbittest.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rb := New(s.URI(), WithTimeout(1))
+	if rb.Error != nil {
+		t.Fatal(rb.Error)
+	}
+	defer rb.Close()
+	s.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		_, e := rb.Apply(ctx, Topology{Exchanges: []TopologyExchange{{Name: "ex1"}}})
+		done <- e
+	}()
+	select {
+	case err = <-done:
+		if err == nil {
+			t.Error("expect apply failed while broker is down")
+		}
+	case <-time.After(3 * time.Second):
+		t.Error("expect apply gave up after ctx done")
+	}
+}
@@ -0,0 +1,71 @@
+package rabbit
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/streadway/amqp"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+var _ propagation.TextMapCarrier = (*headerCarrier)(nil)
+
+// headerCarrier adapts amqp.Table to propagation.TextMapCarrier
+type headerCarrier amqp.Table
+
+func (h headerCarrier) Get(key string) string {
+	switch v := h[key].(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case nil:
+		return ""
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+func (h headerCarrier) Set(key, value string) {
+	h[key] = value
+}
+
+// Add amqp header has single value, so it only sets when key not exists
+func (h headerCarrier) Add(key, value string) {
+	if _, ok := h[key]; !ok {
+		h[key] = value
+	}
+}
+
+func (h headerCarrier) Keys() []string {
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+func (h headerCarrier) Values(key string) []string {
+	if _, ok := h[key]; !ok {
+		return nil
+	}
+	return []string{h.Get(key)}
+}
+
+// injectHeaders copy headers and write trace context of ctx into it
+func (rb *Rabbit) injectHeaders(ctx context.Context, headers amqp.Table) amqp.Table {
+	ns := make(amqp.Table, len(headers))
+	for k, v := range headers {
+		ns[k] = v
+	}
+	rb.ops.propagator.Inject(ctx, headerCarrier(ns))
+	return ns
+}
+
+// extractHeaders read trace context from headers into ctx
+func (rb *Rabbit) extractHeaders(ctx context.Context, headers amqp.Table) context.Context {
+	if len(headers) == 0 {
+		return ctx
+	}
+	return rb.ops.propagator.Extract(ctx, headerCarrier(headers))
+}
@@ -0,0 +1,37 @@
+package rabbit
+
+import (
+	"context"
+	"testing"
+
+	"github.com/streadway/amqp"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestRabbit_injectHeaders(t *testing.T) {
+	rb := &Rabbit{ops: *getOptionsOrSetDefault(nil)}
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+	})
+	ctx := trace.ContextWithSpanContext(context.Background(), sc)
+
+	headers := amqp.Table{"x-retry-count": int32(1)}
+	ns := rb.injectHeaders(ctx, headers)
+	if _, ok := headers["traceparent"]; ok {
+		t.Errorf("origin headers should not be changed")
+		return
+	}
+	if ns["x-retry-count"] != int32(1) {
+		t.Errorf("expect x-retry-count 1, got %v", ns["x-retry-count"])
+		return
+	}
+
+	got := trace.SpanContextFromContext(rb.extractHeaders(context.Background(), ns))
+	if got.TraceID() != traceID || got.SpanID() != spanID || !got.IsRemote() {
+		t.Errorf("expect remote span %s/%s, got %s/%s", traceID, spanID, got.TraceID(), got.SpanID())
+	}
+}
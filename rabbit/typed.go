@@ -0,0 +1,134 @@
+package rabbit
+
+import (
+	"context"
+	"encoding/json"
+	"mime"
+	"reflect"
+	"strings"
+
+	"github.com/go-cinch/common/log"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/streadway/amqp"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	ContentTypeJSON     = "application/json"
+	ContentTypeProtobuf = "application/x-protobuf"
+)
+
+// PublishTyped publish m as protobuf if it is a proto.Message, otherwise as json,
+// content type, message id, timestamp and type are set automatically
+func PublishTyped[T any](ex *Exchange, m T, options ...func(*PublishOptions)) (err error) {
+	var b []byte
+	contentType := ContentTypeJSON
+	msgType := typeName[T]()
+	if pm, ok := any(m).(proto.Message); ok {
+		contentType = ContentTypeProtobuf
+		msgType = string(pm.ProtoReflect().Descriptor().FullName())
+		b, err = proto.Marshal(pm)
+	} else {
+		b, err = json.Marshal(m)
+	}
+	if err != nil {
+		err = errors.WithStack(err)
+		return
+	}
+	ops := []func(*PublishOptions){
+		WithPublishContentType(contentType),
+		WithPublishMessageID(uuid.NewString()),
+		WithPublishType(msgType),
+	}
+	// custom options can override defaults
+	err = ex.PublishByte(b, append(ops, options...)...)
+	return
+}
+
+// ConsumeTyped like Consume, delivery body is decoded to T by content type(json or protobuf),
+// delivery that can not be decoded is rejected without requeue, so it will be moved to dead letter queue
+func ConsumeTyped[T any](qu *Queue, handler func(ctx context.Context, q string, m T, d amqp.Delivery) bool, options ...func(*ConsumeOptions)) (co *Consumer, err error) {
+	if handler == nil {
+		err = errors.Errorf("handler is nil")
+		return
+	}
+	co, err = qu.consume(func(ctx context.Context, q string, d amqp.Delivery) action {
+		m, e := decode[T](d)
+		if e != nil {
+			log.
+				WithContext(ctx).
+				WithError(e).
+				WithFields(log.Fields{
+					"queue":        q,
+					"content.type": d.ContentType,
+					"message.id":   d.MessageId,
+				}).
+				Error("decode delivery failed, reject it")
+			return actionReject
+		}
+		if handler(ctx, q, m, d) {
+			return actionAck
+		}
+		return actionNack
+	}, options...)
+	return
+}
+
+func decode[T any](d amqp.Delivery) (m T, err error) {
+	// allocate when T is a pointer, such as *pb.Msg
+	if t := reflect.TypeOf(m); t != nil && t.Kind() == reflect.Ptr {
+		m = reflect.New(t.Elem()).Interface().(T)
+	}
+	pm, isProto := any(m).(proto.Message)
+	if !isProto {
+		pm, isProto = any(&m).(proto.Message)
+	}
+	switch mediaType(d.ContentType) {
+	case "application/x-protobuf", "application/protobuf", "application/vnd.google.protobuf":
+		if !isProto {
+			err = errors.Errorf("%s is not a proto message", typeName[T]())
+			return
+		}
+		err = proto.Unmarshal(d.Body, pm)
+	case "application/json", "text/json":
+		err = decodeJSON(d.Body, &m, pm, isProto)
+	case "", "text/plain":
+		// unknown content type, decide by T
+		if isProto {
+			err = proto.Unmarshal(d.Body, pm)
+		} else {
+			err = json.Unmarshal(d.Body, &m)
+		}
+	default:
+		if strings.HasSuffix(mediaType(d.ContentType), "+json") {
+			err = decodeJSON(d.Body, &m, pm, isProto)
+			return
+		}
+		err = errors.Errorf("unsupported content type: %s", d.ContentType)
+	}
+	if err != nil {
+		err = errors.WithStack(err)
+	}
+	return
+}
+
+func decodeJSON(b []byte, v interface{}, pm proto.Message, isProto bool) error {
+	if isProto {
+		return protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(b, pm)
+	}
+	return json.Unmarshal(b, v)
+}
+
+func mediaType(contentType string) string {
+	t, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return strings.ToLower(strings.TrimSpace(contentType))
+	}
+	return t
+}
+
+func typeName[T any]() string {
+	return reflect.TypeOf((*T)(nil)).Elem().String()
+}
@@ -0,0 +1,75 @@
+package rabbit
+
+import (
+	"testing"
+
+	"github.com/streadway/amqp"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+type order struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func TestDecode(t *testing.T) {
+	m1, err := decode[order](amqp.Delivery{
+		ContentType: "application/json; charset=utf-8",
+		Body:        []byte(`{"id":1,"name":"a"}`),
+	})
+	if err != nil || m1.ID != 1 || m1.Name != "a" {
+		t.Errorf("decode json failed, got %v, %v", m1, err)
+		return
+	}
+
+	m2, err := decode[*order](amqp.Delivery{
+		Body: []byte(`{"id":2}`),
+	})
+	if err != nil || m2.ID != 2 {
+		t.Errorf("decode json to pointer failed, got %v, %v", m2, err)
+		return
+	}
+
+	b, _ := proto.Marshal(wrapperspb.String("ok"))
+	m3, err := decode[*wrapperspb.StringValue](amqp.Delivery{
+		ContentType: ContentTypeProtobuf,
+		Body:        b,
+	})
+	if err != nil || m3.GetValue() != "ok" {
+		t.Errorf("decode proto failed, got %v, %v", m3, err)
+		return
+	}
+
+	m4, err := decode[*wrapperspb.StringValue](amqp.Delivery{
+		ContentType: ContentTypeJSON,
+		Body:        []byte(`"ok"`),
+	})
+	if err != nil || m4.GetValue() != "ok" {
+		t.Errorf("decode proto json failed, got %v, %v", m4, err)
+		return
+	}
+
+	_, err = decode[order](amqp.Delivery{
+		ContentType: ContentTypeProtobuf,
+		Body:        b,
+	})
+	if err == nil {
+		t.Errorf("expect err when decode proto to non proto type")
+		return
+	}
+
+	_, err = decode[order](amqp.Delivery{
+		ContentType: "application/xml",
+		Body:        []byte("<a/>"),
+	})
+	if err == nil {
+		t.Errorf("expect unsupported content type err")
+	}
+}
+
+func TestTypeName(t *testing.T) {
+	if v := typeName[order](); v != "rabbit.order" {
+		t.Errorf("expect rabbit.order, got %s", v)
+	}
+}
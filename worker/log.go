@@ -5,44 +5,32 @@ import (
 	"github.com/hibiken/asynq"
 )
 
+// loggerName asynq logs are written to named logger, level can be changed at runtime by log.LevelHandler
+const loggerName = "worker"
+
 var _ asynq.Logger = (*myLogger)(nil)
 
+// myLogger asynq logger, named wrapper is created once in New
 type myLogger struct {
+	log *log.Wrapper
 }
 
-func (myLogger) Debug(args ...interface{}) {
-	log.Debug(args...)
-}
-
-func (myLogger) Info(args ...interface{}) {
-	log.Info(args...)
+func (l myLogger) Debug(args ...interface{}) {
+	l.log.Debug(args...)
 }
 
-func (myLogger) Warn(args ...interface{}) {
-	log.Warn(args...)
+func (l myLogger) Info(args ...interface{}) {
+	l.log.Info(args...)
 }
 
-func (myLogger) Error(args ...interface{}) {
-	log.Error(args...)
+func (l myLogger) Warn(args ...interface{}) {
+	l.log.Warn(args...)
 }
 
-func (myLogger) Fatal(args ...interface{}) {
-	log.Fatal(args...)
+func (l myLogger) Error(args ...interface{}) {
+	l.log.Error(args...)
 }
 
-func levelToAsynq(l log.Level) asynq.LogLevel {
-	switch l {
-	case log.FatalLevel:
-		return asynq.FatalLevel
-	case log.ErrorLevel:
-		return asynq.ErrorLevel
-	case log.WarnLevel:
-		return asynq.WarnLevel
-	case log.InfoLevel:
-		return asynq.InfoLevel
-	case log.DebugLevel, log.TraceLevel:
-		return asynq.DebugLevel
-	default:
-		return asynq.InfoLevel
-	}
+func (l myLogger) Fatal(args ...interface{}) {
+	l.log.Fatal(args...)
 }
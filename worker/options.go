@@ -23,7 +23,7 @@ type Options struct {
 	timeout                  int
 	delayedTaskCheckInterval time.Duration
 	scanTaskInterval         time.Duration
-	logLevel                 log.Level
+	logLevel                 *log.Level
 	lockerTTL                time.Duration
 	lockerRetryCount         int
 	lockerRetryInterval      time.Duration
@@ -131,9 +131,11 @@ func WithScanTaskInterval(duration time.Duration) func(*Options) {
 	}
 }
 
+// WithLogLevel level of asynq logs, it's the level of log.Named("worker") and can be changed at runtime,
+// default inherit from the default logger
 func WithLogLevel(level log.Level) func(*Options) {
 	return func(options *Options) {
-		getOptionsOrSetDefault(options).logLevel = level
+		getOptionsOrSetDefault(options).logLevel = &level
 	}
 }
 
@@ -188,7 +190,6 @@ func getOptionsOrSetDefault(options *Options) *Options {
 			delayedTaskCheckInterval: 5 * time.Second,
 			// if u need run seconds expr, must set this param < one period
 			scanTaskInterval:    time.Second,
-			logLevel:            log.InfoLevel,
 			lockerTTL:           time.Minute,
 			lockerRetryCount:    40,
 			lockerRetryInterval: 25 * time.Millisecond,
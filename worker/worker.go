@@ -194,6 +194,10 @@ func New(options ...func(*Options)) (tk *Worker) {
 		f(ops)
 	}
 	tk = &Worker{}
+	logger := myLogger{log: log.Named(loggerName)}
+	if ops.logLevel != nil {
+		logger.log.SetLevel(*ops.logLevel)
+	}
 	if ops.redisURI == "" {
 		tk.Error = errors.WithStack(ErrRedisNil)
 		return
@@ -268,8 +272,9 @@ func New(options ...func(*Options)) (tk *Worker) {
 			},
 			RetryDelayFunc:           ops.retryDelayFunc,
 			DelayedTaskCheckInterval: ops.delayedTaskCheckInterval,
-			Logger:                   myLogger{},
-			LogLevel:                 levelToAsynq(ops.logLevel),
+			Logger:                   logger,
+			// level is checked by named logger
+			LogLevel: asynq.DebugLevel,
 		},
 	)
 	go func() {
@@ -670,7 +675,6 @@ func (wk Worker) RestoreCronExpr(ctx context.Context, uid string) (err error) {
 	return
 }
 
-
 func (wk Worker) processed(ctx context.Context, uid string) {
 	lock, err := wk.lock(ctx, strings.Join([]string{"processed", uid}, "."), RunOptions{
 		lockerTTL:           wk.ops.lockerTTL,